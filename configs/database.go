@@ -2,14 +2,20 @@ package configs
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
+	_ "github.com/jackc/pgx/v5/stdlib"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
+	_ "modernc.org/sqlite"
 )
 
+const defaultMongoDBName = "likr"
+
 func ConnectDB() (*mongo.Database, error) {
 	cfg, err := LoadEnvs()
 	if err != nil {
@@ -31,7 +37,67 @@ func ConnectDB() (*mongo.Database, error) {
 
 	fmt.Println("Database connection established!")
 
-	database := client.Database("likr")
+	dbName := cfg.MONGO_DB_NAME
+	if dbName == "" {
+		dbName = defaultMongoDBName
+	}
+
+	database := client.Database(dbName)
 
 	return database, nil
 }
+
+// ConnectSQL opens the relational database selected by DB_DRIVER ("sqlite"
+// or "postgres") using the DATABASE_URL DSN.
+func ConnectSQL() (*sql.DB, error) {
+	cfg, err := LoadEnvs()
+	if err != nil {
+		return nil, fmt.Errorf("error loading env: %w", err)
+	}
+
+	dsn := cfg.DATABASE_URL
+
+	var driverName string
+	switch cfg.DB_DRIVER {
+	case "sqlite":
+		driverName = "sqlite"
+
+		// Store times in a sortable layout so range queries and ORDER BY
+		// on timestamp columns behave like they do on Postgres.
+		if !strings.Contains(dsn, "_time_format=") {
+			separator := "?"
+			if strings.Contains(dsn, "?") {
+				separator = "&"
+			}
+
+			dsn += separator + "_time_format=sqlite"
+		}
+	case "postgres":
+		driverName = "pgx"
+	default:
+		return nil, fmt.Errorf("unsupported sql driver: %s", cfg.DB_DRIVER)
+	}
+
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to the database: %w", err)
+	}
+
+	if cfg.DB_DRIVER == "sqlite" {
+		// SQLite allows a single writer, and every connection to a
+		// ":memory:" database gets its own empty copy.
+		db.SetMaxOpenConns(1)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	err = db.PingContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("internal server error: %w", err)
+	}
+
+	fmt.Println("Database connection established!")
+
+	return db, nil
+}
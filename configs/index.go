@@ -12,9 +12,21 @@ type config struct {
 	API_KEY                          string
 	BASE_URL                         string
 	GIN_MODE                         string
+	DB_DRIVER                        string
+	DEDUPE_URLS                      string
 	MONGO_URI                        string
+	MONGO_DB_NAME                    string
+	DATABASE_URL                     string
+	GEOIP_DB_PATH                    string
+	STORE_VISITOR_IPS                string
+	TRUSTED_PROXIES                  string
 	CLIENT_URL                       string
 	JWT_SECRET                       string
+	SLUG_SALT                        string
+	SLUG_LENGTH                      string
+	SLUG_STRATEGY                    string
+	TRASH_RETENTION_DAYS             string
+	VISIT_RETENTION_DAYS             string
 	MAILTRAP_AUTH                    string
 	IMAGEKIT_PUBLIC_KEY              string
 	URL_REDIRECT_PREFIX              string
@@ -40,9 +52,21 @@ func LoadEnvs() (config, error) {
 	cfg.API_KEY = os.Getenv("API_KEY")
 	cfg.BASE_URL = os.Getenv("BASE_URL")
 	cfg.GIN_MODE = os.Getenv("GIN_MODE")
+	cfg.DB_DRIVER = os.Getenv("DB_DRIVER")
+	cfg.DEDUPE_URLS = os.Getenv("DEDUPE_URLS")
 	cfg.MONGO_URI = os.Getenv("MONGO_URI")
+	cfg.MONGO_DB_NAME = os.Getenv("MONGO_DB_NAME")
+	cfg.DATABASE_URL = os.Getenv("DATABASE_URL")
+	cfg.GEOIP_DB_PATH = os.Getenv("GEOIP_DB_PATH")
+	cfg.STORE_VISITOR_IPS = os.Getenv("STORE_VISITOR_IPS")
+	cfg.TRUSTED_PROXIES = os.Getenv("TRUSTED_PROXIES")
 	cfg.CLIENT_URL = os.Getenv("CLIENT_URL")
 	cfg.JWT_SECRET = os.Getenv("JWT_SECRET")
+	cfg.SLUG_SALT = os.Getenv("SLUG_SALT")
+	cfg.SLUG_LENGTH = os.Getenv("SLUG_LENGTH")
+	cfg.SLUG_STRATEGY = os.Getenv("SLUG_STRATEGY")
+	cfg.TRASH_RETENTION_DAYS = os.Getenv("TRASH_RETENTION_DAYS")
+	cfg.VISIT_RETENTION_DAYS = os.Getenv("VISIT_RETENTION_DAYS")
 	cfg.MAILTRAP_AUTH = os.Getenv("MAILTRAP_AUTH")
 	cfg.URL_REDIRECT_PREFIX = os.Getenv("URL_REDIRECT_PREFIX")
 	cfg.IMAGEKIT_PUBLIC_KEY = os.Getenv("IMAGEKIT_PUBLIC_KEY")
@@ -0,0 +1,173 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Origho-precious/url-shortener/go/configs"
+	"github.com/Origho-precious/url-shortener/go/models"
+	"github.com/Origho-precious/url-shortener/go/utils"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// defaultAnalyticsRange is how far back analytics go when no from date is
+// given.
+const defaultAnalyticsRange = 30 * 24 * time.Hour
+
+// parseAnalyticsQuery reads the interval, timezone, from, to and
+// includeBots query parameters. It answers 400 itself when they are invalid.
+func parseAnalyticsQuery(c *gin.Context) (models.AnalyticsQuery, bool) {
+	timezone := c.Query("timezone")
+
+	location, err := utils.LoadTimezone(timezone)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return models.AnalyticsQuery{}, false
+	}
+
+	from, ok := parseLinkTime(c, "from", c.Query("from"), timezone)
+	if !ok {
+		return models.AnalyticsQuery{}, false
+	}
+
+	to, ok := parseLinkTime(c, "to", c.Query("to"), timezone)
+	if !ok {
+		return models.AnalyticsQuery{}, false
+	}
+
+	if to.IsZero() {
+		to = time.Now()
+	}
+
+	if from.IsZero() {
+		from = to.Add(-defaultAnalyticsRange)
+	}
+
+	query := models.AnalyticsQuery{
+		From:        from,
+		To:          to,
+		Interval:    c.DefaultQuery("interval", models.AnalyticsDay),
+		Location:    location,
+		IncludeBots: c.Query("includeBots") == "true",
+	}
+
+	err = models.ValidateAnalyticsQuery(query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return models.AnalyticsQuery{}, false
+	}
+
+	return query, true
+}
+
+func GetUrlAnalytics(c *gin.Context, urlS *models.UrlService) {
+	userId := c.MustGet("userId").(string)
+	objectID, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "internal server error",
+		})
+		return
+	}
+
+	urlID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no matching document found"})
+		return
+	}
+
+	query, ok := parseAnalyticsQuery(c)
+	if !ok {
+		return
+	}
+
+	analytics, err := urlS.GetUrlAnalytics(urlID, objectID, query)
+	if err != nil {
+		var statusCode int
+
+		if err.Error() == "internal server error" {
+			statusCode = http.StatusInternalServerError
+		} else {
+			statusCode = http.StatusNotFound
+		}
+
+		c.JSON(statusCode, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"response": gin.H{
+			"from":           query.From,
+			"to":             query.To,
+			"interval":       query.Interval,
+			"timezone":       query.Location.String(),
+			"clicks":         analytics.Clicks,
+			"uniqueVisitors": analytics.UniqueVisitors,
+			"series":         analytics.Series,
+			"browsers":       analytics.Browsers,
+			"devices":        analytics.Devices,
+			"referrers":      analytics.Referrers,
+			"locations":      analytics.Locations,
+		},
+		"message": "Url analytics",
+	})
+}
+
+func GetAccountAnalytics(c *gin.Context, urlS *models.UrlService) {
+	cfg, err := configs.LoadEnvs()
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "internal server error",
+		})
+		return
+	}
+
+	userId := c.MustGet("userId").(string)
+	objectID, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "internal server error",
+		})
+		return
+	}
+
+	query, ok := parseAnalyticsQuery(c)
+	if !ok {
+		return
+	}
+
+	analytics, err := urlS.GetAccountAnalytics(objectID, query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	topLinks := []any{}
+	for _, link := range analytics.TopLinks {
+		topLinks = append(topLinks, map[string]any{
+			"id":          link.Url.ID.Hex(),
+			"shortUrl":    cfg.URL_REDIRECT_PREFIX + "/" + link.Url.ShortUrlSlug,
+			"originalUrl": link.Url.OriginalUrl,
+			"clicks":      link.Clicks,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"response": gin.H{
+			"from":      query.From,
+			"to":        query.To,
+			"interval":  query.Interval,
+			"timezone":  query.Location.String(),
+			"clicks":    analytics.Clicks,
+			"series":    analytics.Series,
+			"topLinks":  topLinks,
+			"referrers": analytics.Referrers,
+			"countries": analytics.Countries,
+			"devices":   analytics.Devices,
+		},
+		"message": "Account analytics",
+	})
+}
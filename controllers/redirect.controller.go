@@ -1,79 +1,302 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	neturl "net/url"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/Origho-precious/url-shortener/go/configs"
 	"github.com/Origho-precious/url-shortener/go/models"
+	"github.com/Origho-precious/url-shortener/go/useragent"
+	"github.com/Origho-precious/url-shortener/go/utils"
 	"github.com/gin-gonic/gin"
 )
 
-func getDeviceTypeFromUserAgent(userAgent string) string {
-	if strings.Contains(strings.ToLower(userAgent), "macintosh") {
-		return "MacOS"
-	} else if strings.Contains(strings.ToLower(userAgent), "windows") {
-		return "Windows"
-	} else if strings.Contains(strings.ToLower(userAgent), "linux") {
-		return "Linux"
-	} else if strings.Contains(strings.ToLower(userAgent), "iphone") ||
-		strings.Contains(strings.ToLower(userAgent), "ipad") {
-		return "iOS"
-	} else if strings.Contains(strings.ToLower(userAgent), "android") {
-		return "Android"
-	} else {
-		return "Unknown"
+const (
+	// unlockCookieTTL is how long a visitor who entered the password of a
+	// url can come back without entering it again.
+	unlockCookieTTL = 15 * time.Minute
+	// Visitors are blocked from guessing the password of a url after
+	// maxFailedUnlocks wrong attempts within failedUnlockWindow, and
+	// everyone is once the url has had maxFailedUrlUnlocks of them, which
+	// holds however many addresses the guesses come from.
+	maxFailedUnlocks    = 5
+	maxFailedUrlUnlocks = 20
+	failedUnlockWindow  = 15 * time.Minute
+	// variantCookieTTL is how long a visitor of a sticky split url keeps
+	// being sent to the same variant.
+	variantCookieTTL = 30 * 24 * time.Hour
+	// previewVariantTTL is how long the continue button of a preview page
+	// leads to the variant it showed.
+	previewVariantTTL = 10 * time.Minute
+)
+
+var (
+	unlockLimiter    = utils.NewFailureLimiter(maxFailedUnlocks, failedUnlockWindow)
+	urlUnlockLimiter = utils.NewFailureLimiter(
+		maxFailedUrlUnlocks, failedUnlockWindow,
+	)
+)
+
+type unavailablePage struct {
+	statusCode int
+	title      string
+	message    string
+}
+
+// unavailablePages are shown to visitors of urls that cannot be visited and
+// have no fallback, keyed by the reason reported by GetOriginalUrl.
+var unavailablePages = map[string]unavailablePage{
+	models.UrlUnknownReason: {
+		statusCode: http.StatusNotFound,
+		title:      "Link not found",
+		message:    "This link does not exist. Check that it was typed correctly.",
+	},
+	models.UrlExpiredReason: {
+		statusCode: http.StatusGone,
+		title:      "Link expired",
+		message:    "This link has expired and no longer leads anywhere.",
+	},
+	models.UrlDeletedReason: {
+		statusCode: http.StatusGone,
+		title:      "Link removed",
+		message:    "This link has been removed by its owner.",
+	},
+	models.UrlInactiveReason: {
+		statusCode: http.StatusNotFound,
+		title:      "Link not active yet",
+		message:    "This link is not active yet. Please try again later.",
+	},
+}
+
+var internalErrorPage = unavailablePage{
+	statusCode: http.StatusInternalServerError,
+	title:      "Something went wrong",
+	message:    "We could not open this link. Please try again in a moment.",
+}
+
+// respondRedirectError sends visitors of a url that cannot be visited to its
+// fallback url, or else shows them an error page. Clients that ask for JSON
+// get the error as JSON instead.
+func respondRedirectError(c *gin.Context, err error) {
+	var unavailableErr *models.UrlUnavailableError
+	if errors.As(err, &unavailableErr) && unavailableErr.FallbackUrl != "" {
+		c.Header("Cache-Control", "no-store")
+		c.Redirect(http.StatusTemporaryRedirect, unavailableErr.FallbackUrl)
+		return
+	}
+
+	page, ok := unavailablePages[err.Error()]
+	if !ok {
+		page = internalErrorPage
 	}
+
+	if c.NegotiateFormat(gin.MIMEHTML, gin.MIMEJSON) == gin.MIMEJSON {
+		c.JSON(page.statusCode, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.HTML(page.statusCode, "unavailable.html", gin.H{
+		"Title":   page.title,
+		"Message": page.message,
+	})
 }
 
-func getBrowserFromUserAgent(userAgent string) string {
-	if strings.Contains(strings.ToLower(userAgent), "chrome") {
-		return "Chrome"
-	} else if strings.Contains(strings.ToLower(userAgent), "firefox") {
-		return "Firefox"
-	} else if strings.Contains(strings.ToLower(userAgent), "safari") {
-		return "Safari"
-	} else if strings.Contains(strings.ToLower(userAgent), "edge") {
-		return "Edge"
-	} else {
-		return "Other"
+func unlockCookieName(url models.Url) string {
+	return "unlock_" + url.ID.Hex()
+}
+
+func variantCookieName(url models.Url) string {
+	return "variant_" + url.ID.Hex()
+}
+
+func isSecureRequest(c *gin.Context) bool {
+	return c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+}
+
+func setVariantCookie(
+	c *gin.Context, url models.Url, variant string, ttl time.Duration,
+) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(
+		variantCookieName(url), variant, int(ttl.Seconds()), "/", "",
+		isSecureRequest(c), true,
+	)
+}
+
+// renderPreview shows the page telling visitors where url takes them, with
+// the Open Graph and Twitter card tags chat apps build link previews from.
+func renderPreview(c *gin.Context, url models.Url, destinationUrl string) {
+	cfg, err := configs.LoadEnvs()
+	if err != nil {
+		log.Println(err)
+		respondRedirectError(c, fmt.Errorf("internal server error"))
+		return
+	}
+
+	domain := destinationUrl
+	parsed, err := neturl.Parse(destinationUrl)
+	if err == nil && parsed.Host != "" {
+		domain = parsed.Hostname()
+	}
+
+	title := url.OGTitle
+	if title == "" {
+		title = domain
+	}
+
+	continueUrl := strings.TrimSuffix(c.Request.URL.Path, "+") + "/continue"
+	if c.Request.URL.RawQuery != "" {
+		continueUrl += "?" + c.Request.URL.RawQuery
 	}
+
+	c.Header("Cache-Control", "private, no-cache")
+	c.HTML(http.StatusOK, "preview.html", gin.H{
+		"Title":       title,
+		"Description": url.OGDescription,
+		"Image":       url.OGImageUrl,
+		"Domain":      domain,
+		"ShortUrl":    cfg.URL_REDIRECT_PREFIX + "/" + url.ShortUrlSlug,
+		"ContinueUrl": continueUrl,
+	})
+}
+
+func renderPasswordForm(c *gin.Context, statusCode int, message string) {
+	c.Header("Cache-Control", "no-store")
+	c.HTML(statusCode, "password.html", gin.H{
+		"Action": c.Request.URL.RequestURI(),
+		"Error":  message,
+	})
 }
 
+// RedirectToLongUrl sends visitors of a short url to its destination, or to
+// a preview page first for urls in preview mode, slugs ending in "+" and
+// chat apps unfurling urls with custom Open Graph fields.
 func RedirectToLongUrl(c *gin.Context, urlS *models.UrlService) {
-	urlSlug := c.Param("slug")
-	urlS.Url.ShortUrlSlug = urlSlug
+	slug := c.Param("slug")
+	forcePreview := strings.HasSuffix(slug, "+")
 
-	response, err := urlS.GetOriginalUrl()
-	if err != nil {
-		var statusCode int
+	followShortUrl(c, urlS, strings.TrimSuffix(slug, "+"), func(
+		url models.Url,
+	) bool {
+		return forcePreview || url.Preview || (models.HasCustomOG(url) &&
+			utils.IsLinkUnfurler(c.Request.UserAgent()))
+	})
+}
 
-		if err.Error() == "internal server error" {
-			statusCode = http.StatusInternalServerError
-		} else {
-			statusCode = http.StatusConflict
-		}
+// ContinueToLongUrl is where the continue button of preview pages leads, it
+// always redirects.
+func ContinueToLongUrl(c *gin.Context, urlS *models.UrlService) {
+	followShortUrl(c, urlS, c.Param("slug"), func(models.Url) bool {
+		return false
+	})
+}
 
-		c.JSON(statusCode, gin.H{"error": err.Error()})
+func followShortUrl(
+	c *gin.Context, urlS *models.UrlService, slug string,
+	showPreview func(url models.Url) bool,
+) {
+	response, err := urlS.GetOriginalUrl(slug)
+	if err != nil {
+		respondRedirectError(c, err)
 		return
 	}
 
+	if response.PasswordHash != "" {
+		token, _ := c.Cookie(unlockCookieName(response))
+		if !urlS.VerifyUnlockToken(response, token) {
+			renderPasswordForm(c, http.StatusOK, "")
+			return
+		}
+	}
+
 	referrer := c.Request.Referer()
 	ipAddress := c.ClientIP()
 	userAgent := c.Request.UserAgent()
-	browser := getBrowserFromUserAgent(userAgent)
-	deviceType := getDeviceTypeFromUserAgent(userAgent)
 
-	urlS.Visit.UrlId = response.ID
-	urlS.Visit.Browser = browser
-	urlS.Visit.Referrer = referrer
-	urlS.Visit.IPAddress = ipAddress
-	urlS.Visit.DeviceType = deviceType
-	urlS.Visit.VisitedAt = time.Now()
+	location, err := urlS.GeoIP.Lookup(ipAddress)
+	if err != nil {
+		log.Println(err)
+	}
+
+	agent := useragent.Parse(userAgent)
+
+	visitor := models.Visitor{
+		// Targeting rules use lower case os names.
+		OS:     strings.ToLower(agent.OS),
+		Device: agent.Device,
+	}
+	visitor.Country = location.Country
+	visitor.StickyVariant, _ = c.Cookie(variantCookieName(response))
+
+	destination := urlS.ResolveDestination(response, visitor)
+
+	destinationUrl, err := models.ApplyQueryParams(
+		response, destination.Url, c.Request.URL.Query(),
+	)
+	if err != nil {
+		// Rather send the visitor on without the query than not at all.
+		log.Println(err)
+		destinationUrl = destination.Url
+	}
+
+	if showPreview(response) {
+		if destination.Variant != "" {
+			// Keep the visitor on the variant the preview showed.
+			setVariantCookie(c, response, destination.Variant, previewVariantTTL)
+		}
+
+		renderPreview(c, response, destinationUrl)
+		return
+	}
+
+	visitedAt := time.Now()
+	// Nobody follows a redirect answered to a HEAD request, so it is counted
+	// like a bot visit and never uses up a click.
+	bot := agent.Device == useragent.DeviceBot ||
+		c.Request.Method == http.MethodHead
+
+	if bot {
+		err = urlS.RegisterBotClick(response)
+	} else {
+		err = urlS.RegisterClick(response, visitedAt)
+	}
+	if err != nil {
+		respondRedirectError(c, err)
+		return
+	}
+
+	visitorHash, err := urlS.VisitorHasher.Hash(ipAddress, userAgent)
+	if err != nil {
+		log.Println(err)
+	}
+
+	visit := models.Visit{
+		UrlId:          response.ID,
+		Browser:        agent.Browser,
+		BrowserVersion: agent.BrowserVersion,
+		OS:             agent.OS,
+		OSVersion:      agent.OSVersion,
+		DeviceType:     agent.Device,
+		Vendor:         agent.Vendor,
+		Referrer:       referrer,
+		IPAddress:      ipAddress,
+		VisitedAt:      visitedAt,
+		Location:       location.String(),
+		Country:        location.Country,
+		Variant:        destination.Variant,
+		Bot:            bot,
+		VisitorHash:    visitorHash,
+	}
 
 	go func() {
-		err := urlS.SaveClickAnalytics()
+		err := urlS.SaveClickAnalytics(visit)
 		if err != nil {
 			fmt.Println(err)
 			return
@@ -81,7 +304,108 @@ func RedirectToLongUrl(c *gin.Context, urlS *models.UrlService) {
 		fmt.Println("Visit analytic saved.")
 	}()
 
-	fmt.Println("Redirecting to: ", response.OriginalUrl)
+	if bot && response.MaxClicks > 0 {
+		// Bots and HEAD requests do not use up clicks, so they are not shown
+		// the destination of click limited urls either.
+		c.Header("Cache-Control", "no-store")
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	fmt.Println("Redirecting to: ", destinationUrl)
+
+	if response.StickySplit && destination.Variant != "" {
+		setVariantCookie(c, response, destination.Variant, variantCookieTTL)
+	}
+
+	if response.MaxClicks > 0 {
+		// A cached redirect would let visitors past the click limit.
+		c.Header("Cache-Control", "no-store")
+	} else if len(response.TargetingRules) > 0 ||
+		len(response.SplitDestinations) > 0 {
+		// The destination depends on where the visitor is or on chance,
+		// neither of which shared caches can account for.
+		c.Header("Cache-Control", "private, no-cache")
+	}
+
+	if len(response.TargetingRules) > 0 {
+		c.Header("Vary", "User-Agent")
+	}
+
+	c.Redirect(http.StatusTemporaryRedirect, destinationUrl)
+}
+
+// UnlockProtectedUrl checks the password posted for a protected url. On
+// success the visitor gets a short-lived cookie and is sent back to the
+// redirect, which then lets them through.
+func UnlockProtectedUrl(c *gin.Context, urlS *models.UrlService) {
+	slug := strings.TrimSuffix(c.Param("slug"), "+")
+
+	response, err := urlS.GetOriginalUrl(slug)
+	if err != nil {
+		respondRedirectError(c, err)
+		return
+	}
+
+	if response.PasswordHash == "" {
+		c.Redirect(http.StatusSeeOther, c.Request.URL.RequestURI())
+		return
+	}
+
+	// The attempt is counted before the password is checked, so
+	// concurrent guesses cannot get past the limits.
+	visitorKey := response.ID.Hex() + "|" + c.ClientIP()
+	urlKey := response.ID.Hex()
+
+	allowed, retryAfter := unlockLimiter.Attempt(visitorKey)
+	if allowed {
+		allowed, retryAfter = urlUnlockLimiter.Attempt(urlKey)
+		if !allowed {
+			unlockLimiter.Forgive(visitorKey)
+		}
+	}
+
+	if !allowed {
+		c.Header("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+		renderPasswordForm(c, http.StatusTooManyRequests,
+			"Too many incorrect attempts, please try again later.",
+		)
+		return
+	}
+
+	var reqBody struct {
+		Password string `form:"password" json:"password"`
+	}
+
+	if err := c.ShouldBind(&reqBody); err != nil || reqBody.Password == "" {
+		unlockLimiter.Forgive(visitorKey)
+		urlUnlockLimiter.Forgive(urlKey)
+		renderPasswordForm(c, http.StatusBadRequest, "Please enter the password.")
+		return
+	}
+
+	if !urlS.CheckUrlPassword(response, reqBody.Password) {
+		renderPasswordForm(c, http.StatusUnauthorized, "Incorrect password.")
+		return
+	}
+
+	unlockLimiter.Reset(visitorKey)
+	urlUnlockLimiter.Forgive(urlKey)
+
+	token, err := urlS.UnlockToken(response, time.Now().Add(unlockCookieTTL))
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "internal server error",
+		})
+		return
+	}
 
-	c.Redirect(http.StatusTemporaryRedirect, response.OriginalUrl)
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(
+		unlockCookieName(response), token, int(unlockCookieTTL.Seconds()), "/",
+		"", isSecureRequest(c), true,
+	)
+	// The query is kept so it can still be passed on to the destination.
+	c.Redirect(http.StatusSeeOther, c.Request.URL.RequestURI())
 }
@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/Origho-precious/url-shortener/go/configs"
+	"github.com/Origho-precious/url-shortener/go/models"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func GetTrashedUrls(c *gin.Context, urlS *models.UrlService) {
+	listUserUrls(c, urlS, urlS.GetTrashedUrls, "Urls in user's trash")
+}
+
+// bindTrashedUrl returns the url id from the path and the id of the
+// authenticated, verified user. It writes the error response itself and
+// reports whether the handler may go on.
+func bindTrashedUrl(c *gin.Context, us *models.UserService) (
+	primitive.ObjectID, primitive.ObjectID, bool,
+) {
+	userId := c.MustGet("userId").(string)
+	objectID, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "internal server error",
+		})
+		return primitive.NilObjectID, primitive.NilObjectID, false
+	}
+
+	urlID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid url id"})
+		return primitive.NilObjectID, primitive.NilObjectID, false
+	}
+
+	us.User.ID = objectID
+
+	userData, err := us.GetUser()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return primitive.NilObjectID, primitive.NilObjectID, false
+	}
+	if !userData.EmailVerified {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "user's email is not yet verified",
+		})
+		return primitive.NilObjectID, primitive.NilObjectID, false
+	}
+
+	return urlID, objectID, true
+}
+
+func HandleUrlRestore(c *gin.Context,
+	urlS *models.UrlService,
+	us *models.UserService,
+) {
+	urlID, userID, ok := bindTrashedUrl(c, us)
+	if !ok {
+		return
+	}
+
+	restored, err := urlS.RestoreUrl(urlID, userID)
+	if err != nil {
+		var statusCode int
+
+		switch err.Error() {
+		case "internal server error":
+			statusCode = http.StatusInternalServerError
+		case "no matching document found in trash":
+			statusCode = http.StatusNotFound
+		default:
+			statusCode = http.StatusConflict
+		}
+
+		c.JSON(statusCode, gin.H{"error": err.Error()})
+		return
+	}
+
+	cfg, err := configs.LoadEnvs()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"response": urlInfo(cfg.URL_REDIRECT_PREFIX, restored),
+		"message":  "Url restored successfully",
+	})
+}
+
+func HandleUrlPurge(c *gin.Context,
+	urlS *models.UrlService,
+	us *models.UserService,
+) {
+	urlID, userID, ok := bindTrashedUrl(c, us)
+	if !ok {
+		return
+	}
+
+	err := urlS.PurgeUrl(urlID, userID)
+	if err != nil {
+		var statusCode int
+
+		if err.Error() == "internal server error" {
+			statusCode = http.StatusInternalServerError
+		} else {
+			statusCode = http.StatusNotFound
+		}
+
+		c.JSON(statusCode, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusNoContent, gin.H{
+		"message": "Url permanently deleted",
+	})
+}
@@ -1,25 +1,209 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
-	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/Origho-precious/url-shortener/go/configs"
 	"github.com/Origho-precious/url-shortener/go/models"
+	"github.com/Origho-precious/url-shortener/go/services"
+	"github.com/Origho-precious/url-shortener/go/utils"
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-func validateURL(url string) bool {
-	domainRegex := regexp.MustCompile(
-		`^(https?:\/\/)?([\w-]+\.)*([\w-]+\.[\w-]{2,})(\/[\w-./?%&=]*)?$`,
-	)
-	return domainRegex.MatchString(url)
+func respondInvalidUrl(c *gin.Context, rawUrl string, err error) {
+	var validationErr *utils.URLValidationError
+	if errors.As(err, &validationErr) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "url is invalid: " + rawUrl,
+			"reason": validationErr.Reason,
+		})
+		return
+	}
+
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
+// parseLinkTime parses a date field of a request body, where an empty value
+// means no date. It answers 400 itself when the value is invalid.
+func parseLinkTime(
+	c *gin.Context, field string, value string, timezone string,
+) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, true
+	}
+
+	parsed, err := utils.ParseLinkTime(value, timezone)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("%s is invalid: %s", field, err.Error()),
+		})
+		return time.Time{}, false
+	}
+
+	return parsed, true
+}
+
+// normalizeOptionalUrl normalises an optional url of a request
+// body. It answers 400 itself when the url is invalid.
+func normalizeOptionalUrl(c *gin.Context, rawUrl string) (string, bool) {
+	if rawUrl == "" {
+		return "", true
+	}
+
+	normalizedUrl, err := utils.NormalizeURL(rawUrl)
+	if err != nil {
+		respondInvalidUrl(c, rawUrl, err)
+		return "", false
+	}
+
+	return normalizedUrl, true
+}
+
+// normalizeTargetingRules normalises and validates the targeting rules of a
+// request body. It answers 400 itself when a rule is invalid.
+func normalizeTargetingRules(
+	c *gin.Context, rules []models.TargetingRule,
+) ([]models.TargetingRule, bool) {
+	normalized := make([]models.TargetingRule, 0, len(rules))
+	for _, rule := range rules {
+		rule.OS = strings.ToLower(strings.TrimSpace(rule.OS))
+		rule.Device = strings.ToLower(strings.TrimSpace(rule.Device))
+		rule.Country = strings.ToUpper(strings.TrimSpace(rule.Country))
+
+		if rule.Url != "" {
+			normalizedUrl, err := utils.NormalizeURL(rule.Url)
+			if err != nil {
+				respondInvalidUrl(c, rule.Url, err)
+				return nil, false
+			}
+
+			rule.Url = normalizedUrl
+		}
+
+		normalized = append(normalized, rule)
+	}
+
+	err := models.ValidateTargetingRules(normalized)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	return normalized, true
+}
+
+// normalizeSplitDestinations names, normalises and validates the split
+// destinations of a request body. It answers 400 itself when one is invalid.
+func normalizeSplitDestinations(
+	c *gin.Context, destinations []models.SplitDestination,
+) ([]models.SplitDestination, bool) {
+	normalized := make([]models.SplitDestination, 0, len(destinations))
+	for _, destination := range destinations {
+		destination.Variant = strings.TrimSpace(destination.Variant)
+
+		if destination.Url != "" {
+			normalizedUrl, err := utils.NormalizeURL(destination.Url)
+			if err != nil {
+				respondInvalidUrl(c, destination.Url, err)
+				return nil, false
+			}
+
+			destination.Url = normalizedUrl
+		}
+
+		normalized = append(normalized, destination)
+	}
+
+	normalized = models.NameSplitVariants(normalized)
+
+	err := models.ValidateSplitDestinations(normalized)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	return normalized, true
+}
+
+// respondPreviewSuffixAlias rejects aliases ending in "+", which would clash
+// with the preview page of the alias without it.
+func respondPreviewSuffixAlias(c *gin.Context, alias string) {
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error": "alias cannot end with +, it opens the preview page: " + alias,
+	})
+}
+
+// urlInfo shapes a url record for API responses.
+func urlInfo(redirectPrefix string, urlRecord models.Url) map[string]any {
+	info := make(map[string]any)
+	info["id"] = urlRecord.ID.Hex()
+	info["shortUrl"] = fmt.Sprintf("%s/%s", redirectPrefix, urlRecord.ShortUrlSlug)
+	info["createdAt"] = urlRecord.CreatedAt
+	info["visitCount"] = urlRecord.VisitCount
+	info["botVisitCount"] = urlRecord.BotVisitCount
+	info["originalUrl"] = urlRecord.OriginalUrl
+	info["customAlias"] = urlRecord.CustomAlias
+	info["qrCodeImageUrl"] = urlRecord.QRCodeImageUrl
+	info["protected"] = urlRecord.PasswordHash != ""
+
+	if urlRecord.MaxClicks == 0 {
+		info["maxClicks"] = nil
+	} else {
+		info["maxClicks"] = urlRecord.MaxClicks
+	}
+
+	if urlRecord.LastVisitedAt.IsZero() {
+		info["lastVisitedAt"] = nil
+	} else {
+		info["lastVisitedAt"] = urlRecord.LastVisitedAt
+	}
+
+	if urlRecord.ExpiresAt.IsZero() {
+		info["expiresAt"] = nil
+	} else {
+		info["expiresAt"] = urlRecord.ExpiresAt
+	}
+
+	info["activatesAt"] = optionalTime(urlRecord.ActivatesAt)
+	info["expiredRedirectUrl"] = urlRecord.ExpiredRedirectUrl
+	info["inactiveRedirectUrl"] = urlRecord.InactiveRedirectUrl
+	info["fallbackUrl"] = urlRecord.FallbackUrl
+
+	if urlRecord.TargetingRules == nil {
+		info["targetingRules"] = []models.TargetingRule{}
+	} else {
+		info["targetingRules"] = urlRecord.TargetingRules
+	}
+
+	if urlRecord.SplitDestinations == nil {
+		info["splitDestinations"] = []models.SplitDestination{}
+	} else {
+		info["splitDestinations"] = urlRecord.SplitDestinations
+	}
+	info["stickySplit"] = urlRecord.StickySplit
+	info["utmSource"] = urlRecord.UtmSource
+	info["utmMedium"] = urlRecord.UtmMedium
+	info["utmCampaign"] = urlRecord.UtmCampaign
+	info["utmTerm"] = urlRecord.UtmTerm
+	info["utmContent"] = urlRecord.UtmContent
+	info["queryPassthrough"] = urlRecord.QueryPassthrough
+	info["preview"] = urlRecord.Preview
+	info["ogTitle"] = urlRecord.OGTitle
+	info["ogDescription"] = urlRecord.OGDescription
+	info["ogImageUrl"] = urlRecord.OGImageUrl
+
+	if urlRecord.Deleted {
+		info["deletedAt"] = optionalTime(urlRecord.DeletedAt)
+	}
+
+	return info
 }
 
 func HandleCreateShortUrl(
@@ -28,9 +212,32 @@ func HandleCreateShortUrl(
 	us *models.UserService,
 ) {
 	var reqBody []struct {
-		Url        string `json:"url" binding:"required"`
-		Alias      string `json:"alias"`
-		ExpiryDate string `json:"expiryDate"`
+		Url                 string                    `json:"url" binding:"required"`
+		Alias               string                    `json:"alias"`
+		ExpiryDate          string                    `json:"expiryDate"`
+		ActivationDate      string                    `json:"activationDate"`
+		Timezone            string                    `json:"timezone"`
+		ExpiredRedirectUrl  string                    `json:"expiredRedirectUrl"`
+		InactiveRedirectUrl string                    `json:"inactiveRedirectUrl"`
+		FallbackUrl         string                    `json:"fallbackUrl"`
+		SlugStrategy        string                    `json:"slugStrategy"`
+		Dedupe              *bool                     `json:"dedupe"`
+		Password            string                    `json:"password"`
+		MaxClicks           int64                     `json:"maxClicks"`
+		OneTime             bool                      `json:"oneTime"`
+		TargetingRules      []models.TargetingRule    `json:"targetingRules"`
+		SplitDestinations   []models.SplitDestination `json:"splitDestinations"`
+		StickySplit         bool                      `json:"stickySplit"`
+		UtmSource           string                    `json:"utmSource"`
+		UtmMedium           string                    `json:"utmMedium"`
+		UtmCampaign         string                    `json:"utmCampaign"`
+		UtmTerm             string                    `json:"utmTerm"`
+		UtmContent          string                    `json:"utmContent"`
+		QueryPassthrough    string                    `json:"queryPassthrough"`
+		Preview             bool                      `json:"preview"`
+		OGTitle             string                    `json:"ogTitle"`
+		OGDescription       string                    `json:"ogDescription"`
+		OGImageUrl          string                    `json:"ogImageUrl"`
 	}
 
 	if err := c.BindJSON(&reqBody); err != nil {
@@ -39,7 +246,7 @@ func HandleCreateShortUrl(
 			"json: cannot unmarshal object into Go value of type []struct",
 		) {
 			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "request body must be an array of objects with properties: url (required), alias (optional), expiryDate(optional)",
+				"error": "request body must be an array of objects with properties: url (required), alias (optional), expiryDate(optional), slugStrategy(optional), activationDate(optional), timezone(optional), expiredRedirectUrl(optional), inactiveRedirectUrl(optional), fallbackUrl(optional), dedupe(optional), password(optional), maxClicks(optional), oneTime(optional), targetingRules(optional), splitDestinations(optional), stickySplit(optional), utmSource(optional), utmMedium(optional), utmCampaign(optional), utmTerm(optional), utmContent(optional), queryPassthrough(optional), preview(optional), ogTitle(optional), ogDescription(optional), ogImageUrl(optional)",
 			})
 			return
 		}
@@ -57,8 +264,6 @@ func HandleCreateShortUrl(
 		return
 	}
 
-	urlS.Url.UserId = objectID
-
 	us.User.ID = objectID
 
 	userData, err := us.GetUser()
@@ -73,12 +278,20 @@ func HandleCreateShortUrl(
 		return
 	}
 
-	var responses []map[string]string
+	cfg, err := configs.LoadEnvs()
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "internal server error",
+		})
+		return
+	}
+
+	var responses []map[string]any
 	for _, item := range reqBody {
-		if !validateURL(item.Url) {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "url is invalid: " + item.Url,
-			})
+		normalizedUrl, err := utils.NormalizeURL(item.Url)
+		if err != nil {
+			respondInvalidUrl(c, item.Url, err)
 			return
 		}
 
@@ -89,33 +302,158 @@ func HandleCreateShortUrl(
 			return
 		}
 
-		urlS.Url.UserId = objectID
-		urlS.Url.OriginalUrl = strings.ToLower(item.Url)
+		if strings.HasSuffix(item.Alias, "+") {
+			respondPreviewSuffixAlias(c, item.Alias)
+			return
+		}
 
-		if item.ExpiryDate != "" {
-			layout := "02-01-2006"
-			parsedDate, err := time.Parse(layout, item.ExpiryDate)
-			if err != nil {
-				log.Println(err)
-				c.JSON(http.StatusInternalServerError, gin.H{
-					"error": "Internal server error",
-				})
-				return
-			}
+		if item.SlugStrategy != "" &&
+			!services.IsValidSlugStrategy(item.SlugStrategy) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "slugStrategy must be one of: " +
+					strings.Join(services.SlugStrategies, ", "),
+			})
+			return
+		}
 
-			urlS.Url.ExpiresAt = parsedDate
-		} else {
-			urlS.Url.ExpiresAt = time.Time{}
+		if len(item.Password) > models.MaxUrlPasswordLength {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf(
+					"password must be at most %d bytes long",
+					models.MaxUrlPasswordLength,
+				),
+			})
+			return
+		}
+
+		if item.MaxClicks < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "maxClicks cannot be negative",
+			})
+			return
+		}
+
+		if item.OneTime && item.MaxClicks > 1 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "oneTime links cannot have a maxClicks above 1",
+			})
+			return
+		}
+
+		// The url is built locally, urlS is shared by concurrent requests.
+		url := models.Url{
+			UserId:      objectID,
+			OriginalUrl: normalizedUrl,
+			MaxClicks:   item.MaxClicks,
+		}
+		if item.OneTime {
+			url.MaxClicks = 1
+		}
+
+		var ok bool
+
+		url.ExpiresAt, ok = parseLinkTime(
+			c, "expiryDate", item.ExpiryDate, item.Timezone,
+		)
+		if !ok {
+			return
+		}
+
+		url.ActivatesAt, ok = parseLinkTime(
+			c, "activationDate", item.ActivationDate, item.Timezone,
+		)
+		if !ok {
+			return
+		}
+
+		if !url.ActivatesAt.IsZero() && !url.ExpiresAt.IsZero() &&
+			!url.ActivatesAt.Before(url.ExpiresAt) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": models.ErrActivationAfterExpiry.Error(),
+			})
+			return
+		}
+
+		url.ExpiredRedirectUrl, ok = normalizeOptionalUrl(
+			c, item.ExpiredRedirectUrl,
+		)
+		if !ok {
+			return
+		}
+
+		url.InactiveRedirectUrl, ok = normalizeOptionalUrl(
+			c, item.InactiveRedirectUrl,
+		)
+		if !ok {
+			return
+		}
+
+		url.FallbackUrl, ok = normalizeOptionalUrl(c, item.FallbackUrl)
+		if !ok {
+			return
+		}
+
+		url.TargetingRules, ok = normalizeTargetingRules(
+			c, item.TargetingRules,
+		)
+		if !ok {
+			return
 		}
 
-		res, err := urlS.CreateShortUrl(item.Alias)
+		url.SplitDestinations, ok = normalizeSplitDestinations(
+			c, item.SplitDestinations,
+		)
+		if !ok {
+			return
+		}
+		url.StickySplit = item.StickySplit
+
+		url.UtmSource = strings.TrimSpace(item.UtmSource)
+		url.UtmMedium = strings.TrimSpace(item.UtmMedium)
+		url.UtmCampaign = strings.TrimSpace(item.UtmCampaign)
+		url.UtmTerm = strings.TrimSpace(item.UtmTerm)
+		url.UtmContent = strings.TrimSpace(item.UtmContent)
+		url.QueryPassthrough = item.QueryPassthrough
+
+		err = models.ValidateQuerySettings(url)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		url.Preview = item.Preview
+		url.OGTitle = strings.TrimSpace(item.OGTitle)
+		url.OGDescription = strings.TrimSpace(item.OGDescription)
+		url.OGImageUrl, ok = normalizeOptionalUrl(c, item.OGImageUrl)
+		if !ok {
+			return
+		}
+
+		err = models.ValidatePreviewSettings(url)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		dedupe := cfg.DEDUPE_URLS == "true"
+		if item.Dedupe != nil {
+			dedupe = *item.Dedupe
+		}
+
+		res, err := urlS.CreateShortUrl(url, models.CreateUrlOptions{
+			Alias:        item.Alias,
+			SlugStrategy: item.SlugStrategy,
+			Dedupe:       dedupe,
+			Password:     item.Password,
+		})
 		if err != nil {
 			var statusCode int
+			var conflictErr *models.AliasConflictError
 
-			if err.Error() == "internal server error" {
-				statusCode = http.StatusInternalServerError
-			} else {
+			if errors.As(err, &conflictErr) {
 				statusCode = http.StatusConflict
+			} else {
+				statusCode = http.StatusInternalServerError
 			}
 
 			c.JSON(statusCode, gin.H{"error": err.Error()})
@@ -153,9 +491,7 @@ func HandleUrlDelete(c *gin.Context,
 		return
 	}
 
-	urlS.Url.ID = urlID
 	us.User.ID = objectID
-	urlS.Url.UserId = objectID
 
 	userData, err := us.GetUser()
 	if err != nil {
@@ -169,7 +505,7 @@ func HandleUrlDelete(c *gin.Context,
 		return
 	}
 
-	err = urlS.DeleteUrl()
+	err = urlS.DeleteUrl(urlID, objectID)
 	if err != nil {
 		var statusCode int
 
@@ -188,7 +524,443 @@ func HandleUrlDelete(c *gin.Context,
 	})
 }
 
+func HandleUrlUpdate(
+	c *gin.Context,
+	urlS *models.UrlService,
+	us *models.UserService,
+) {
+	var reqBody struct {
+		Url                 *string                    `json:"url"`
+		Alias               *string                    `json:"alias"`
+		ExpiryDate          *string                    `json:"expiryDate"`
+		ActivationDate      *string                    `json:"activationDate"`
+		Timezone            string                     `json:"timezone"`
+		ExpiredRedirectUrl  *string                    `json:"expiredRedirectUrl"`
+		InactiveRedirectUrl *string                    `json:"inactiveRedirectUrl"`
+		FallbackUrl         *string                    `json:"fallbackUrl"`
+		Password            *string                    `json:"password"`
+		MaxClicks           *int64                     `json:"maxClicks"`
+		TargetingRules      *[]models.TargetingRule    `json:"targetingRules"`
+		SplitDestinations   *[]models.SplitDestination `json:"splitDestinations"`
+		StickySplit         *bool                      `json:"stickySplit"`
+		UtmSource           *string                    `json:"utmSource"`
+		UtmMedium           *string                    `json:"utmMedium"`
+		UtmCampaign         *string                    `json:"utmCampaign"`
+		UtmTerm             *string                    `json:"utmTerm"`
+		UtmContent          *string                    `json:"utmContent"`
+		QueryPassthrough    *string                    `json:"queryPassthrough"`
+		Preview             *bool                      `json:"preview"`
+		OGTitle             *string                    `json:"ogTitle"`
+		OGDescription       *string                    `json:"ogDescription"`
+		OGImageUrl          *string                    `json:"ogImageUrl"`
+	}
+
+	if err := c.BindJSON(&reqBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var update models.UrlUpdate
+
+	if reqBody.Url != nil {
+		normalizedUrl, err := utils.NormalizeURL(*reqBody.Url)
+		if err != nil {
+			respondInvalidUrl(c, *reqBody.Url, err)
+			return
+		}
+
+		update.OriginalUrl = &normalizedUrl
+	}
+
+	if reqBody.Alias != nil {
+		if len(*reqBody.Alias) < 4 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "alias needs to be at least 4 characters long: " + *reqBody.Alias,
+			})
+			return
+		}
+
+		if strings.HasSuffix(*reqBody.Alias, "+") {
+			respondPreviewSuffixAlias(c, *reqBody.Alias)
+			return
+		}
+
+		update.Alias = reqBody.Alias
+	}
+
+	// Empty dates and fallback urls remove the current ones.
+	if reqBody.ExpiryDate != nil {
+		expiresAt, ok := parseLinkTime(
+			c, "expiryDate", *reqBody.ExpiryDate, reqBody.Timezone,
+		)
+		if !ok {
+			return
+		}
+
+		update.ExpiresAt = &expiresAt
+	}
+
+	if reqBody.ActivationDate != nil {
+		activatesAt, ok := parseLinkTime(
+			c, "activationDate", *reqBody.ActivationDate, reqBody.Timezone,
+		)
+		if !ok {
+			return
+		}
+
+		update.ActivatesAt = &activatesAt
+	}
+
+	if reqBody.ExpiredRedirectUrl != nil {
+		fallbackUrl, ok := normalizeOptionalUrl(c, *reqBody.ExpiredRedirectUrl)
+		if !ok {
+			return
+		}
+
+		update.ExpiredRedirectUrl = &fallbackUrl
+	}
+
+	if reqBody.InactiveRedirectUrl != nil {
+		fallbackUrl, ok := normalizeOptionalUrl(c, *reqBody.InactiveRedirectUrl)
+		if !ok {
+			return
+		}
+
+		update.InactiveRedirectUrl = &fallbackUrl
+	}
+
+	if reqBody.FallbackUrl != nil {
+		fallbackUrl, ok := normalizeOptionalUrl(c, *reqBody.FallbackUrl)
+		if !ok {
+			return
+		}
+
+		update.FallbackUrl = &fallbackUrl
+	}
+
+	if reqBody.Password != nil {
+		// An empty password removes the protection.
+		if len(*reqBody.Password) > models.MaxUrlPasswordLength {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf(
+					"password must be at most %d bytes long",
+					models.MaxUrlPasswordLength,
+				),
+			})
+			return
+		}
+
+		update.Password = reqBody.Password
+	}
+
+	if reqBody.MaxClicks != nil {
+		// A maxClicks of 0 removes the limit.
+		if *reqBody.MaxClicks < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "maxClicks cannot be negative",
+			})
+			return
+		}
+
+		update.MaxClicks = reqBody.MaxClicks
+	}
+
+	if reqBody.TargetingRules != nil {
+		// An empty list removes every rule.
+		targetingRules, ok := normalizeTargetingRules(c, *reqBody.TargetingRules)
+		if !ok {
+			return
+		}
+
+		update.TargetingRules = &targetingRules
+	}
+
+	if reqBody.SplitDestinations != nil {
+		// An empty list stops splitting traffic.
+		splitDestinations, ok := normalizeSplitDestinations(
+			c, *reqBody.SplitDestinations,
+		)
+		if !ok {
+			return
+		}
+
+		update.SplitDestinations = &splitDestinations
+	}
+
+	update.StickySplit = reqBody.StickySplit
+
+	// Empty UTM parameters are removed, an empty queryPassthrough stops
+	// forwarding the query.
+	var querySettings models.Url
+	for _, field := range []struct {
+		value  *string
+		target *string
+		update **string
+	}{
+		{reqBody.UtmSource, &querySettings.UtmSource, &update.UtmSource},
+		{reqBody.UtmMedium, &querySettings.UtmMedium, &update.UtmMedium},
+		{reqBody.UtmCampaign, &querySettings.UtmCampaign, &update.UtmCampaign},
+		{reqBody.UtmTerm, &querySettings.UtmTerm, &update.UtmTerm},
+		{reqBody.UtmContent, &querySettings.UtmContent, &update.UtmContent},
+		{
+			reqBody.QueryPassthrough, &querySettings.QueryPassthrough,
+			&update.QueryPassthrough,
+		},
+	} {
+		if field.value != nil {
+			*field.target = strings.TrimSpace(*field.value)
+			*field.update = field.target
+		}
+	}
+
+	err := models.ValidateQuerySettings(querySettings)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	update.Preview = reqBody.Preview
+
+	// Empty Open Graph fields fall back to the defaults of the preview page.
+	var previewSettings models.Url
+	if reqBody.OGTitle != nil {
+		previewSettings.OGTitle = strings.TrimSpace(*reqBody.OGTitle)
+		update.OGTitle = &previewSettings.OGTitle
+	}
+
+	if reqBody.OGDescription != nil {
+		previewSettings.OGDescription = strings.TrimSpace(*reqBody.OGDescription)
+		update.OGDescription = &previewSettings.OGDescription
+	}
+
+	if reqBody.OGImageUrl != nil {
+		ogImageUrl, ok := normalizeOptionalUrl(c, *reqBody.OGImageUrl)
+		if !ok {
+			return
+		}
+
+		update.OGImageUrl = &ogImageUrl
+	}
+
+	err = models.ValidatePreviewSettings(previewSettings)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if update == (models.UrlUpdate{}) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "request body must contain at least one of: url, alias, expiryDate, activationDate, expiredRedirectUrl, inactiveRedirectUrl, fallbackUrl, password, maxClicks, targetingRules, splitDestinations, stickySplit, utmSource, utmMedium, utmCampaign, utmTerm, utmContent, queryPassthrough, preview, ogTitle, ogDescription, ogImageUrl",
+		})
+		return
+	}
+
+	userId := c.MustGet("userId").(string)
+	objectID, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "internal server error",
+		})
+		return
+	}
+
+	urlID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no matching document found"})
+		return
+	}
+
+	us.User.ID = objectID
+
+	userData, err := us.GetUser()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !userData.EmailVerified {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "user's email is not yet verified",
+		})
+		return
+	}
+
+	urlRecord, err := urlS.UpdateUrl(urlID, objectID, update)
+	if err != nil {
+		var statusCode int
+		var conflictErr *models.AliasConflictError
+
+		if errors.As(err, &conflictErr) {
+			statusCode = http.StatusConflict
+		} else if err == models.ErrActivationAfterExpiry {
+			statusCode = http.StatusBadRequest
+		} else if err.Error() == "internal server error" {
+			statusCode = http.StatusInternalServerError
+		} else {
+			statusCode = http.StatusNotFound
+		}
+
+		c.JSON(statusCode, gin.H{"error": err.Error()})
+		return
+	}
+
+	cfg, err := configs.LoadEnvs()
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"response": urlInfo(cfg.URL_REDIRECT_PREFIX, urlRecord),
+		"message":  "Url updated successfully",
+	})
+}
+
+// optionalTime returns nil for zero times so they serialise as null.
+func optionalTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+
+	return t
+}
+
+func GetUrlHistory(c *gin.Context, urlS *models.UrlService) {
+	userId := c.MustGet("userId").(string)
+	objectID, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "internal server error",
+		})
+		return
+	}
+
+	urlID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no matching document found"})
+		return
+	}
+
+	revisions, err := urlS.GetUrlHistory(urlID, objectID)
+	if err != nil {
+		var statusCode int
+
+		if err.Error() == "internal server error" {
+			statusCode = http.StatusInternalServerError
+		} else {
+			statusCode = http.StatusNotFound
+		}
+
+		c.JSON(statusCode, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := []any{}
+	for _, revision := range revisions {
+		response = append(response, map[string]any{
+			"id":             revision.ID.Hex(),
+			"changedBy":      revision.ChangedBy.Hex(),
+			"changedAt":      revision.ChangedAt,
+			"oldOriginalUrl": revision.OldOriginalUrl,
+			"newOriginalUrl": revision.NewOriginalUrl,
+			"oldExpiresAt":   optionalTime(revision.OldExpiresAt),
+			"newExpiresAt":   optionalTime(revision.NewExpiresAt),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"response": response,
+		"message":  "Url revision history",
+	})
+}
+
+func HandleUrlRollback(
+	c *gin.Context,
+	urlS *models.UrlService,
+	us *models.UserService,
+) {
+	userId := c.MustGet("userId").(string)
+	objectID, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "internal server error",
+		})
+		return
+	}
+
+	urlID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no matching document found"})
+		return
+	}
+
+	revisionID, err := primitive.ObjectIDFromHex(c.Param("revisionId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no matching revision found"})
+		return
+	}
+
+	us.User.ID = objectID
+
+	userData, err := us.GetUser()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !userData.EmailVerified {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "user's email is not yet verified",
+		})
+		return
+	}
+
+	urlRecord, err := urlS.RollbackUrl(urlID, objectID, revisionID)
+	if err != nil {
+		var statusCode int
+
+		if err == models.ErrActivationAfterExpiry {
+			statusCode = http.StatusBadRequest
+		} else if err.Error() == "internal server error" {
+			statusCode = http.StatusInternalServerError
+		} else {
+			statusCode = http.StatusNotFound
+		}
+
+		c.JSON(statusCode, gin.H{"error": err.Error()})
+		return
+	}
+
+	cfg, err := configs.LoadEnvs()
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"response": urlInfo(cfg.URL_REDIRECT_PREFIX, urlRecord),
+		"message":  "Url rolled back successfully",
+	})
+}
+
 func GetUrlsByUserID(c *gin.Context, urlS *models.UrlService) {
+	listUserUrls(c, urlS, urlS.GetUrlsByUser, "Urls generated by user")
+}
+
+// listUserUrls responds with one page of the authenticated user's urls as
+// returned by fetch.
+func listUserUrls(
+	c *gin.Context,
+	urlS *models.UrlService,
+	fetch func(userId primitive.ObjectID, page int, limit int) (
+		[]models.Url, int64, error,
+	),
+	message string,
+) {
 	const (
 		defaultPage     = 1
 		defaultPageSize = 10
@@ -220,9 +992,7 @@ func GetUrlsByUserID(c *gin.Context, urlS *models.UrlService) {
 		return
 	}
 
-	urlS.Url.UserId = objectID
-
-	data, total, err := urlS.GetUrlsByUser(page, limit)
+	data, total, err := fetch(objectID, page, limit)
 	if err != nil {
 		log.Println(err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -238,39 +1008,22 @@ func GetUrlsByUserID(c *gin.Context, urlS *models.UrlService) {
 		return
 	}
 
+	// Bot visits are left out of visitCount unless asked for.
+	includeBots := c.Query("includeBots") == "true"
+
 	var response []any
 	for _, urlRecord := range data {
-		shortUrl := fmt.Sprintf(
-			"%s/%s", cfg.URL_REDIRECT_PREFIX, urlRecord.ShortUrlSlug,
-		)
-
-		urlInfo := make(map[string]interface{})
-		urlInfo["id"] = urlRecord.ID.Hex()
-		urlInfo["shortUrl"] = shortUrl
-		urlInfo["createdAt"] = urlRecord.CreatedAt
-		urlInfo["visitCount"] = urlRecord.VisitCount
-		urlInfo["originalUrl"] = urlRecord.OriginalUrl
-		urlInfo["customAlias"] = urlRecord.CustomAlias
-		urlInfo["qrCodeImageUrl"] = urlRecord.QRCodeImageUrl
-
-		if urlRecord.LastVisitedAt.IsZero() {
-			urlInfo["lastVisitedAt"] = nil
-		} else {
-			urlInfo["lastVisitedAt"] = urlRecord.LastVisitedAt
-		}
-
-		if urlRecord.ExpiresAt.IsZero() {
-			urlInfo["expiresAt"] = nil
-		} else {
-			urlInfo["expiresAt"] = urlRecord.ExpiresAt
+		info := urlInfo(cfg.URL_REDIRECT_PREFIX, urlRecord)
+		if includeBots {
+			info["visitCount"] = urlRecord.VisitCount + urlRecord.BotVisitCount
 		}
 
-		response = append(response, urlInfo)
+		response = append(response, info)
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"response": response,
 		"total":    total,
-		"message":  "Urls generated by user",
+		"message":  message,
 	})
 }
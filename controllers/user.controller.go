@@ -282,6 +282,7 @@ func GetUserProfile(c *gin.Context, us *models.UserService) {
 			"fullName":      userData.FullName,
 			"createdAt":     userData.CreatedAt,
 			"emailVerified": userData.EmailVerified,
+			"fallbackUrl":   userData.FallbackUrl,
 		},
 	})
 }
@@ -333,3 +334,59 @@ func HandleUserFullNameEdit(c *gin.Context, us *models.UserService) {
 		},
 	})
 }
+
+// HandleUserFallbackUrlEdit sets where visitors of the user's unavailable
+// urls are sent when a url has no fallback of its own. An empty fallbackUrl
+// removes it.
+func HandleUserFallbackUrlEdit(c *gin.Context, us *models.UserService) {
+	var reqBody struct {
+		FallbackUrl *string `json:"fallbackUrl" binding:"required"`
+	}
+
+	if err := c.BindJSON(&reqBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": utils.Capitalise(err.Error())})
+		return
+	}
+
+	fallbackUrl := ""
+	if *reqBody.FallbackUrl != "" {
+		normalizedUrl, err := utils.NormalizeURL(*reqBody.FallbackUrl)
+		if err != nil {
+			respondInvalidUrl(c, *reqBody.FallbackUrl, err)
+			return
+		}
+
+		fallbackUrl = normalizedUrl
+	}
+
+	us.User.FallbackUrl = fallbackUrl
+
+	userId := c.MustGet("userId").(string)
+	objectID, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Internal server error",
+		})
+		return
+	}
+
+	us.User.ID = objectID
+
+	userData, err := us.UpdateUserFallbackUrl()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": utils.Capitalise(err.Error())})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Fallback url updated successfully.",
+		"response": map[string]any{
+			"id":            userId,
+			"email":         userData.Email,
+			"fullName":      userData.FullName,
+			"createdAt":     userData.CreatedAt,
+			"emailVerified": userData.EmailVerified,
+			"fallbackUrl":   userData.FallbackUrl,
+		},
+	})
+}
@@ -0,0 +1,74 @@
+package jobs
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/Origho-precious/url-shortener/go/configs"
+	"github.com/Origho-precious/url-shortener/go/models"
+)
+
+const (
+	defaultVisitRetentionDays = 90
+	visitRollupInterval       = time.Hour
+	// visitRollupDelay leaves time for the visits of the hour that just
+	// ended to be saved before it is rolled up.
+	visitRollupDelay = 5 * time.Minute
+)
+
+// StartVisitRollups rolls visits up into hourly and daily counts once an
+// hour, then deletes the raw visits older than VISIT_RETENTION_DAYS. A
+// retention of 0 keeps raw visits forever.
+func StartVisitRollups(repos *models.Repositories) error {
+	cfg, err := configs.LoadEnvs()
+	if err != nil {
+		return err
+	}
+
+	retentionDays := defaultVisitRetentionDays
+	if cfg.VISIT_RETENTION_DAYS != "" {
+		retentionDays, err = strconv.Atoi(cfg.VISIT_RETENTION_DAYS)
+		if err != nil || retentionDays < 0 {
+			return fmt.Errorf(
+				"invalid VISIT_RETENTION_DAYS %q", cfg.VISIT_RETENTION_DAYS,
+			)
+		}
+	}
+
+	retention := time.Duration(retentionDays) * 24 * time.Hour
+	urlService := &models.UrlService{
+		VisitRepository:  repos.Visits,
+		RollupRepository: repos.VisitRollups,
+	}
+
+	go func() {
+		ticker := time.NewTicker(visitRollupInterval)
+		defer ticker.Stop()
+
+		for {
+			err := urlService.RollUpVisits(time.Now().Add(-visitRollupDelay))
+			if err != nil {
+				log.Println(err)
+			}
+
+			if retention > 0 {
+				deleted, err := urlService.DeleteOldVisits(
+					time.Now().Add(-retention),
+				)
+				if err != nil {
+					log.Println(err)
+				}
+
+				if deleted > 0 {
+					log.Printf("deleted %d rolled up visits", deleted)
+				}
+			}
+
+			<-ticker.C
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package jobs
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/Origho-precious/url-shortener/go/configs"
+	"github.com/Origho-precious/url-shortener/go/models"
+)
+
+const (
+	defaultTrashRetentionDays = 30
+	trashPurgeInterval        = time.Hour
+)
+
+// StartTrashPurger permanently deletes urls that have been in the trash for
+// longer than TRASH_RETENTION_DAYS, checking once an hour. A retention of 0
+// keeps trashed urls until their owner purges them.
+func StartTrashPurger(repos *models.Repositories) error {
+	cfg, err := configs.LoadEnvs()
+	if err != nil {
+		return err
+	}
+
+	retentionDays := defaultTrashRetentionDays
+	if cfg.TRASH_RETENTION_DAYS != "" {
+		retentionDays, err = strconv.Atoi(cfg.TRASH_RETENTION_DAYS)
+		if err != nil || retentionDays < 0 {
+			return fmt.Errorf(
+				"invalid TRASH_RETENTION_DAYS %q", cfg.TRASH_RETENTION_DAYS,
+			)
+		}
+	}
+
+	if retentionDays == 0 {
+		return nil
+	}
+
+	retention := time.Duration(retentionDays) * 24 * time.Hour
+	urlService := &models.UrlService{
+		UrlRepository:      repos.Urls,
+		VisitRepository:    repos.Visits,
+		RevisionRepository: repos.UrlRevisions,
+		RollupRepository:   repos.VisitRollups,
+	}
+
+	go func() {
+		ticker := time.NewTicker(trashPurgeInterval)
+		defer ticker.Stop()
+
+		for {
+			purged, err := urlService.PurgeExpiredTrash(time.Now().Add(-retention))
+			if err != nil {
+				log.Println(err)
+			}
+
+			if purged > 0 {
+				log.Printf("purged %d urls from the trash", purged)
+			}
+
+			<-ticker.C
+		}
+	}()
+
+	return nil
+}
@@ -2,23 +2,74 @@ package main
 
 import (
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/Origho-precious/url-shortener/go/configs"
+	"github.com/Origho-precious/url-shortener/go/jobs"
+	"github.com/Origho-precious/url-shortener/go/repositories"
 	"github.com/Origho-precious/url-shortener/go/routes"
+	"github.com/Origho-precious/url-shortener/go/services"
+	"github.com/Origho-precious/url-shortener/go/templates"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
 var methods = []string{"GET", "POST", "PATCH", "DELETE", "OPTIONS"}
 
+// trustedProxies splits the TRUSTED_PROXIES env, an empty one trusts none.
+func trustedProxies(value string) []string {
+	var proxies []string
+	for _, proxy := range strings.Split(value, ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+
+	return proxies
+}
+
 func main() {
-	database, err := configs.ConnectDB()
+	repos, err := repositories.Open()
+	if err != nil {
+		panic(err)
+	}
+
+	err = jobs.StartTrashPurger(repos)
+	if err != nil {
+		panic(err)
+	}
+
+	err = jobs.StartVisitRollups(repos)
+	if err != nil {
+		panic(err)
+	}
+
+	geoIP, err := services.NewGeoIP()
+	if err != nil {
+		panic(err)
+	}
+	defer geoIP.Close()
+
+	pages, err := templates.Load()
+	if err != nil {
+		panic(err)
+	}
+
+	cfg, err := configs.LoadEnvs()
 	if err != nil {
 		panic(err)
 	}
 
 	r := gin.Default()
+	r.SetHTMLTemplate(pages)
+
+	// Client ips are taken from X-Forwarded-For only when the request came
+	// through one of these proxies, anyone can set the header otherwise.
+	err = r.SetTrustedProxies(trustedProxies(cfg.TRUSTED_PROXIES))
+	if err != nil {
+		panic(err)
+	}
 
 	r.Use(cors.New(cors.Config{
 		MaxAge:           12 * time.Hour,
@@ -33,9 +84,9 @@ func main() {
 		c.JSON(http.StatusOK, gin.H{"message": "Hello there :)"})
 	})
 
-	routes.UserRouter(r, database)
+	routes.UserRouter(r, repos)
 
-	routes.UrlRouter(r, database)
+	routes.UrlRouter(r, repos, geoIP)
 
 	r.NoRoute(func(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"message": "Oops, not Found :("})
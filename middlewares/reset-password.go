@@ -5,13 +5,14 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/Origho-precious/url-shortener/go/models"
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func ValidateResetPasswordToken(rpwdCol *mongo.Collection) gin.HandlerFunc {
+func ValidateResetPasswordToken(
+	rpwdRepo models.ForgotPasswordRepository,
+) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
@@ -36,14 +37,14 @@ func ValidateResetPasswordToken(rpwdCol *mongo.Collection) gin.HandlerFunc {
 		}
 
 		// Check for record associated to this token in DB
-		res := rpwdCol.FindOne(ctx, bson.M{"_id": objectID})
-		if res.Err() == mongo.ErrNoDocuments {
+		_, err = rpwdRepo.FindByID(ctx, objectID)
+		if err == models.ErrNotFound {
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error": "invalid or expired reset password token",
 			})
 			c.Abort()
 			return
-		} else if res.Err() != nil {
+		} else if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error": "internal server error",
 			})
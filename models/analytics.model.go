@@ -0,0 +1,411 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"log"
+	neturl "net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Intervals clicks can be bucketed by. Weeks start on Monday.
+const (
+	AnalyticsHour  = "hour"
+	AnalyticsDay   = "day"
+	AnalyticsWeek  = "week"
+	AnalyticsMonth = "month"
+)
+
+var AnalyticsIntervals = []string{
+	AnalyticsHour, AnalyticsDay, AnalyticsWeek, AnalyticsMonth,
+}
+
+// MaxAnalyticsBuckets caps the length of a time series, e.g. an hourly series
+// can cover about six weeks.
+const MaxAnalyticsBuckets = 1000
+
+// AnalyticsQuery selects the visits made in [From, To). Buckets start at
+// midnight, the first of the month etc. in Location.
+type AnalyticsQuery struct {
+	From        time.Time
+	To          time.Time
+	Interval    string
+	Location    *time.Location
+	IncludeBots bool
+}
+
+type AnalyticsBucket struct {
+	Start          time.Time `json:"start"`
+	Clicks         int64     `json:"clicks"`
+	UniqueVisitors int64     `json:"uniqueVisitors"`
+}
+
+// AnalyticsCount is the number of clicks sharing a browser, referrer etc.
+type AnalyticsCount struct {
+	Value  string `json:"value"`
+	Clicks int64  `json:"clicks"`
+}
+
+// Analytics sums up the visits matching an AnalyticsQuery. Breakdowns are
+// sorted by clicks, most first.
+//
+// Visitor hashes rotate daily, so a visitor coming back on another day is
+// counted again in UniqueVisitors. Buckets in timezones other than UTC sum
+// the unique visitors of the UTC hours they cover, and over rolled-up ranges
+// the unique visitors of each hour or day are summed, so they are counted
+// again per hour or day.
+type Analytics struct {
+	Clicks         int64             `json:"clicks"`
+	UniqueVisitors int64             `json:"uniqueVisitors"`
+	Series         []AnalyticsBucket `json:"series"`
+	Browsers       []AnalyticsCount  `json:"browsers"`
+	Devices        []AnalyticsCount  `json:"devices"`
+	Referrers      []AnalyticsCount  `json:"referrers"`
+	Locations      []AnalyticsCount  `json:"locations"`
+}
+
+// ValidateAnalyticsQuery checks the interval and range of a query.
+func ValidateAnalyticsQuery(query AnalyticsQuery) error {
+	validInterval := false
+	for _, interval := range AnalyticsIntervals {
+		validInterval = validInterval || query.Interval == interval
+	}
+
+	if !validInterval {
+		return fmt.Errorf(
+			"interval must be one of %s", strings.Join(AnalyticsIntervals, ", "),
+		)
+	}
+
+	if !query.From.Before(query.To) {
+		return fmt.Errorf("from must be before to")
+	}
+
+	buckets := 0
+	start := bucketStart(query.From, query.Interval, query.Location)
+	for ; start.Before(query.To); start = nextBucket(start, query.Interval) {
+		buckets++
+		if buckets > MaxAnalyticsBuckets {
+			return fmt.Errorf(
+				"range is too long for %s buckets, it can cover at most %d",
+				query.Interval, MaxAnalyticsBuckets,
+			)
+		}
+	}
+
+	return nil
+}
+
+// GetUrlAnalytics sums up the visits to the url identified by urlId,
+// provided it belongs to userId. The query is expected to be valid.
+// Rollups are read for the whole hours of the range that have been rolled
+// up, and the visit repository counts the raw visits of the rest.
+func (urlS *UrlService) GetUrlAnalytics(
+	urlId primitive.ObjectID,
+	userId primitive.ObjectID,
+	query AnalyticsQuery,
+) (Analytics, error) {
+	_, err := urlS.UrlRepository.FindByID(
+		context.TODO(), urlId, userId, false,
+	)
+	if err != nil {
+		if err == ErrNotFound {
+			return Analytics{}, fmt.Errorf("no matching document found")
+		}
+
+		log.Println(err)
+		return Analytics{}, fmt.Errorf("internal server error")
+	}
+
+	builder := newAnalyticsBuilder(query)
+
+	filter := VisitFilter{
+		UrlIds:      []primitive.ObjectID{urlId},
+		From:        query.From,
+		To:          query.To,
+		IncludeBots: query.IncludeBots,
+	}
+
+	rolledUp, ok, err := urlS.rolledUpPart(filter)
+	if err != nil {
+		log.Println(err)
+		return Analytics{}, fmt.Errorf("internal server error")
+	}
+
+	if ok {
+		for _, dimension := range []string{
+			RollupTotal, RollupBrowser, RollupDevice, RollupReferrer,
+			RollupLocation,
+		} {
+			rollups, err := urlS.findRollups(query, rolledUp, dimension)
+			if err != nil {
+				log.Println(err)
+				return Analytics{}, fmt.Errorf("internal server error")
+			}
+
+			for _, rollup := range rollups {
+				builder.addRollup(rollup)
+			}
+		}
+	}
+
+	for _, raw := range rawParts(filter, rolledUp, ok) {
+		for _, dimension := range builder.visitDimensions() {
+			counts, err := urlS.VisitRepository.CountBy(
+				context.TODO(), raw, dimension,
+			)
+			if err != nil {
+				log.Println(err)
+				return Analytics{}, fmt.Errorf("internal server error")
+			}
+
+			builder.addCounts(dimension, counts)
+		}
+	}
+
+	return builder.build(), nil
+}
+
+// analyticsBuilder adds the counts of visits and rollups up into Analytics.
+type analyticsBuilder struct {
+	query          AnalyticsQuery
+	clicks         int64
+	uniqueVisitors int64
+	series         []AnalyticsBucket
+	buckets        map[int64]int
+	browsers       map[string]int64
+	devices        map[string]int64
+	referrers      map[string]int64
+	locations      map[string]int64
+}
+
+func newAnalyticsBuilder(query AnalyticsQuery) *analyticsBuilder {
+	builder := &analyticsBuilder{
+		query:     query,
+		series:    []AnalyticsBucket{},
+		buckets:   make(map[int64]int),
+		browsers:  make(map[string]int64),
+		devices:   make(map[string]int64),
+		referrers: make(map[string]int64),
+		locations: make(map[string]int64),
+	}
+
+	// Empty buckets are part of the series too, so it can be charted as is.
+	start := bucketStart(query.From, query.Interval, query.Location)
+	for ; start.Before(query.To); start = nextBucket(start, query.Interval) {
+		builder.buckets[start.Unix()] = len(builder.series)
+		builder.series = append(builder.series, AnalyticsBucket{Start: start})
+	}
+
+	return builder
+}
+
+// hourlySeries reports whether the series is counted by UTC hour rather
+// than UTC day, the way findRollups picks rollups.
+func (b *analyticsBuilder) hourlySeries() bool {
+	return b.query.Interval == AnalyticsHour ||
+		b.query.Location.String() != "UTC"
+}
+
+// visitDimensions returns the dimensions raw visits are counted by. Daily
+// counts give the totals, and the series too unless it is counted by hour.
+func (b *analyticsBuilder) visitDimensions() []string {
+	dimensions := []string{
+		VisitDay, VisitBrowser, VisitDevice, VisitReferrer, VisitLocation,
+	}
+
+	if b.hourlySeries() {
+		dimensions = append(dimensions, VisitHour)
+	}
+
+	return dimensions
+}
+
+// addCounts adds the counts of raw visits by dimension, one of
+// visitDimensions.
+func (b *analyticsBuilder) addCounts(dimension string, counts []VisitCount) {
+	for _, count := range counts {
+		switch dimension {
+		case VisitDay:
+			// Visitor hashes rotate daily, so the visitors of different
+			// days are different visitors.
+			b.clicks += count.Count
+			b.uniqueVisitors += count.Visitors
+
+			if !b.hourlySeries() {
+				b.addToSeries(VisitDayLayout, count)
+			}
+		case VisitHour:
+			b.addToSeries(VisitHourLayout, count)
+		case VisitBrowser:
+			b.browsers[orUnknown(count.Value)] += count.Count
+		case VisitDevice:
+			b.devices[deviceLabel(count.Value)] += count.Count
+		case VisitReferrer:
+			b.referrers[referrerDomain(count.Value)] += count.Count
+		case VisitLocation:
+			b.locations[orUnknown(count.Value)] += count.Count
+		}
+	}
+}
+
+// addToSeries adds count, whose value is the UTC hour or day formatted with
+// layout, to the bucket it falls in.
+func (b *analyticsBuilder) addToSeries(layout string, count VisitCount) {
+	start, err := time.Parse(layout, count.Value)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	// The first hour or day starts before the range does when the range
+	// starts part way through it. Only its visits made in the range were
+	// counted.
+	if start.Before(b.query.From) {
+		start = b.query.From
+	}
+
+	start = bucketStart(start, b.query.Interval, b.query.Location)
+	if i, ok := b.buckets[start.Unix()]; ok {
+		b.series[i].Clicks += count.Count
+		b.series[i].UniqueVisitors += count.Visitors
+	}
+}
+
+// addRollup adds the counts of a rollup. The rollup goes into the bucket its
+// start falls in.
+func (b *analyticsBuilder) addRollup(rollup VisitRollup) {
+	start := bucketStart(rollup.Start, b.query.Interval, b.query.Location)
+	i, ok := b.buckets[start.Unix()]
+	if !ok {
+		return
+	}
+
+	switch rollup.Dimension {
+	case RollupTotal:
+		b.clicks += rollup.Clicks
+		b.uniqueVisitors += rollup.UniqueVisitors
+		b.series[i].Clicks += rollup.Clicks
+		b.series[i].UniqueVisitors += rollup.UniqueVisitors
+	case RollupBrowser:
+		b.browsers[rollup.Value] += rollup.Clicks
+	case RollupDevice:
+		b.devices[rollup.Value] += rollup.Clicks
+	case RollupReferrer:
+		b.referrers[rollup.Value] += rollup.Clicks
+	case RollupLocation:
+		b.locations[rollup.Value] += rollup.Clicks
+	}
+}
+
+func (b *analyticsBuilder) build() Analytics {
+	return Analytics{
+		Clicks:         b.clicks,
+		UniqueVisitors: b.uniqueVisitors,
+		Series:         b.series,
+		Browsers:       sortedCounts(b.browsers),
+		Devices:        sortedCounts(b.devices),
+		Referrers:      sortedCounts(b.referrers),
+		Locations:      sortedCounts(b.locations),
+	}
+}
+
+// bucketStart returns the start of the bucket t falls in.
+func bucketStart(t time.Time, interval string, location *time.Location) time.Time {
+	t = t.In(location)
+	year, month, day := t.Date()
+
+	switch interval {
+	case AnalyticsHour:
+		return time.Date(year, month, day, t.Hour(), 0, 0, 0, location)
+	case AnalyticsWeek:
+		daysSinceMonday := (int(t.Weekday()) + 6) % 7
+		return time.Date(year, month, day-daysSinceMonday, 0, 0, 0, 0, location)
+	case AnalyticsMonth:
+		return time.Date(year, month, 1, 0, 0, 0, 0, location)
+	default:
+		return time.Date(year, month, day, 0, 0, 0, 0, location)
+	}
+}
+
+// nextBucket returns the start of the bucket after the one starting at start.
+// Days are added by date so buckets keep starting at midnight across daylight
+// saving changes.
+func nextBucket(start time.Time, interval string) time.Time {
+	switch interval {
+	case AnalyticsHour:
+		return start.Add(time.Hour)
+	case AnalyticsWeek:
+		return start.AddDate(0, 0, 7)
+	case AnalyticsMonth:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// referrerDomain returns the host a visitor came from without its www.
+// prefix, or "direct" when the visit had no referrer.
+func referrerDomain(referrer string) string {
+	if referrer == "" {
+		return "direct"
+	}
+
+	parsed, err := neturl.Parse(referrer)
+	if err != nil || parsed.Hostname() == "" {
+		return "unknown"
+	}
+
+	return strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+}
+
+// legacyDeviceTypes maps the operating systems visits recorded as their
+// device type before user agents were parsed to the device class they most
+// likely were. Android phones and Linux desktops were both recorded as
+// Linux, so those stay unknown.
+var legacyDeviceTypes = map[string]string{
+	"MacOS":   "desktop",
+	"Windows": "desktop",
+	"iOS":     "mobile",
+	"Android": "mobile",
+	"Linux":   "",
+	"Unknown": "",
+}
+
+// deviceLabel returns the device class of a visit's device type.
+func deviceLabel(deviceType string) string {
+	if class, ok := legacyDeviceTypes[deviceType]; ok {
+		deviceType = class
+	}
+
+	return orUnknown(deviceType)
+}
+
+func orUnknown(value string) string {
+	if value == "" {
+		return "unknown"
+	}
+
+	return value
+}
+
+func sortedCounts(counts map[string]int64) []AnalyticsCount {
+	sorted := make([]AnalyticsCount, 0, len(counts))
+	for value, clicks := range counts {
+		sorted = append(sorted, AnalyticsCount{Value: value, Clicks: clicks})
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Clicks != sorted[j].Clicks {
+			return sorted[i].Clicks > sorted[j].Clicks
+		}
+
+		return sorted[i].Value < sorted[j].Value
+	})
+
+	return sorted
+}
@@ -0,0 +1,236 @@
+package models_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Origho-precious/url-shortener/go/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TestAnalyticsMapLegacyDeviceTypes checks that visits which stored an
+// operating system as their device type are counted by device class, from
+// raw visits and from rollups alike.
+func TestAnalyticsMapLegacyDeviceTypes(t *testing.T) {
+	visitedAt := time.Date(2026, 3, 2, 10, 15, 0, 0, time.UTC)
+	deviceTypes := []string{
+		"MacOS", "Windows", "iOS", "Android", "Linux", "Unknown", "mobile",
+		"desktop",
+	}
+	want := []models.AnalyticsCount{
+		{Value: "desktop", Clicks: 3},
+		{Value: "mobile", Clicks: 3},
+		{Value: "unknown", Clicks: 2},
+	}
+
+	query := models.AnalyticsQuery{
+		From:     visitedAt.Truncate(24 * time.Hour),
+		To:       visitedAt.Truncate(24*time.Hour).AddDate(0, 0, 1),
+		Interval: models.AnalyticsDay,
+		Location: time.UTC,
+	}
+
+	for name, repos := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			userId := primitive.NewObjectID()
+
+			urlId, err := repos.Urls.Insert(ctx, models.Url{
+				UserId:       userId,
+				OriginalUrl:  "https://example.com",
+				ShortUrlSlug: "legacy",
+				CreatedAt:    visitedAt.AddDate(0, 0, -1),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, deviceType := range deviceTypes {
+				_, err = repos.Visits.Insert(ctx, models.Visit{
+					UrlId:       urlId,
+					VisitedAt:   visitedAt,
+					DeviceType:  deviceType,
+					VisitorHash: deviceType,
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			for _, stage := range []string{"raw", "rolled up"} {
+				if stage == "rolled up" {
+					urlService := newTestUrlService(repos)
+					err = urlService.RollUpVisits(query.To)
+					if err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				urlService := newTestUrlService(repos)
+
+				url, err := urlService.GetUrlAnalytics(urlId, userId, query)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if !reflect.DeepEqual(url.Devices, want) {
+					t.Errorf("%s url devices: got %+v, want %+v",
+						stage, url.Devices, want)
+				}
+
+				account, err := urlService.GetAccountAnalytics(userId, query)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if !reflect.DeepEqual(account.Devices, want) {
+					t.Errorf("%s account devices: got %+v, want %+v",
+						stage, account.Devices, want)
+				}
+			}
+		})
+	}
+}
+
+func TestGetUrlAnalytics(t *testing.T) {
+	day := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	visits := []models.Visit{
+		{VisitedAt: day.Add(9*time.Hour + 10*time.Minute), VisitorHash: "a",
+			Browser: "Chrome", DeviceType: "desktop",
+			Referrer: "https://www.google.com/search"},
+		{VisitedAt: day.Add(9*time.Hour + 50*time.Minute), VisitorHash: "a",
+			Browser: "Chrome", DeviceType: "desktop",
+			Referrer: "https://google.com/"},
+		{VisitedAt: day.Add(11*time.Hour + 20*time.Minute), VisitorHash: "b",
+			Browser: "Safari", DeviceType: "mobile",
+			Location: "Lagos, Nigeria"},
+		{VisitedAt: day.Add(11*time.Hour + 30*time.Minute),
+			IPAddress: "198.51.100.1", Browser: "Firefox"},
+		{VisitedAt: day.Add(11*time.Hour + 40*time.Minute),
+			IPAddress: "198.51.100.1", Browser: "Firefox"},
+		{VisitedAt: day.Add(12 * time.Hour), VisitorHash: "c",
+			Browser: "Googlebot", DeviceType: "bot", Bot: true},
+		{VisitedAt: day.Add(32 * time.Hour), VisitorHash: "a",
+			Browser: "Chrome", DeviceType: "desktop"},
+	}
+
+	tests := []struct {
+		name  string
+		query models.AnalyticsQuery
+		want  models.Analytics
+	}{
+		{
+			name: "days",
+			query: models.AnalyticsQuery{
+				From:     day,
+				To:       day.AddDate(0, 0, 2),
+				Interval: models.AnalyticsDay,
+				Location: time.UTC,
+			},
+			want: models.Analytics{
+				Clicks:         6,
+				UniqueVisitors: 4,
+				Series: []models.AnalyticsBucket{
+					{Start: day, Clicks: 5, UniqueVisitors: 3},
+					{Start: day.AddDate(0, 0, 1), Clicks: 1, UniqueVisitors: 1},
+				},
+				Browsers: []models.AnalyticsCount{
+					{Value: "Chrome", Clicks: 3},
+					{Value: "Firefox", Clicks: 2},
+					{Value: "Safari", Clicks: 1},
+				},
+				Devices: []models.AnalyticsCount{
+					{Value: "desktop", Clicks: 3},
+					{Value: "unknown", Clicks: 2},
+					{Value: "mobile", Clicks: 1},
+				},
+				Referrers: []models.AnalyticsCount{
+					{Value: "direct", Clicks: 4},
+					{Value: "google.com", Clicks: 2},
+				},
+				Locations: []models.AnalyticsCount{
+					{Value: "unknown", Clicks: 5},
+					{Value: "Lagos, Nigeria", Clicks: 1},
+				},
+			},
+		},
+		{
+			name: "hours with bots",
+			query: models.AnalyticsQuery{
+				From:        day.Add(9*time.Hour + 30*time.Minute),
+				To:          day.Add(12*time.Hour + 30*time.Minute),
+				Interval:    models.AnalyticsHour,
+				Location:    time.UTC,
+				IncludeBots: true,
+			},
+			want: models.Analytics{
+				Clicks:         5,
+				UniqueVisitors: 4,
+				Series: []models.AnalyticsBucket{
+					{Start: day.Add(9 * time.Hour), Clicks: 1, UniqueVisitors: 1},
+					{Start: day.Add(10 * time.Hour)},
+					{Start: day.Add(11 * time.Hour), Clicks: 3, UniqueVisitors: 2},
+					{Start: day.Add(12 * time.Hour), Clicks: 1, UniqueVisitors: 1},
+				},
+				Browsers: []models.AnalyticsCount{
+					{Value: "Firefox", Clicks: 2},
+					{Value: "Chrome", Clicks: 1},
+					{Value: "Googlebot", Clicks: 1},
+					{Value: "Safari", Clicks: 1},
+				},
+				Devices: []models.AnalyticsCount{
+					{Value: "unknown", Clicks: 2},
+					{Value: "bot", Clicks: 1},
+					{Value: "desktop", Clicks: 1},
+					{Value: "mobile", Clicks: 1},
+				},
+				Referrers: []models.AnalyticsCount{
+					{Value: "direct", Clicks: 4},
+					{Value: "google.com", Clicks: 1},
+				},
+				Locations: []models.AnalyticsCount{
+					{Value: "unknown", Clicks: 4},
+					{Value: "Lagos, Nigeria", Clicks: 1},
+				},
+			},
+		},
+	}
+
+	for name, repos := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			userId := primitive.NewObjectID()
+
+			urlId, err := repos.Urls.Insert(ctx, models.Url{
+				UserId:       userId,
+				OriginalUrl:  "https://example.com",
+				ShortUrlSlug: "analytics",
+				CreatedAt:    day.AddDate(0, 0, -1),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, visit := range visits {
+				visit.UrlId = urlId
+				if _, err = repos.Visits.Insert(ctx, visit); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			for _, test := range tests {
+				urlService := newTestUrlService(repos)
+				got, err := urlService.GetUrlAnalytics(urlId, userId, test.query)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if !reflect.DeepEqual(got, test.want) {
+					t.Errorf("%s:\ngot  %+v\nwant %+v", test.name, got, test.want)
+				}
+			}
+		})
+	}
+}
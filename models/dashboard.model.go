@@ -0,0 +1,267 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Dimensions VisitRepository.CountBy can group visits by.
+const (
+	// VisitHour values are the start of the UTC hour of a visit, formatted
+	// with VisitHourLayout.
+	VisitHour = "hour"
+	// VisitDay values are the UTC day of a visit, formatted with
+	// VisitDayLayout.
+	VisitDay = "day"
+	// VisitUrl values are the hex ids of urls.
+	VisitUrl      = "url"
+	VisitReferrer = "referrer"
+	VisitCountry  = "country"
+	VisitDevice   = "device"
+	VisitBrowser  = "browser"
+	VisitLocation = "location"
+)
+
+const (
+	VisitHourLayout = "2006-01-02T15:00:00Z"
+	VisitDayLayout  = "2006-01-02"
+)
+
+// DashboardTopEntries is how many links, referrers and countries the account
+// dashboard lists.
+const DashboardTopEntries = 10
+
+// VisitFilter selects the visits to UrlIds made in [From, To).
+type VisitFilter struct {
+	UrlIds      []primitive.ObjectID
+	From        time.Time
+	To          time.Time
+	IncludeBots bool
+}
+
+// VisitCount is the number of visits sharing a value of a dimension, and
+// how many distinct visitors made them.
+type VisitCount struct {
+	Value    string
+	Count    int64
+	Visitors int64
+}
+
+type ClickBucket struct {
+	Start  time.Time `json:"start"`
+	Clicks int64     `json:"clicks"`
+}
+
+type LinkClicks struct {
+	Url    Url
+	Clicks int64
+}
+
+// AccountAnalytics sums up the visits to all of a user's links. Devices
+// lists every device type, the other breakdowns only the top entries.
+type AccountAnalytics struct {
+	Clicks    int64
+	Series    []ClickBucket
+	TopLinks  []LinkClicks
+	Referrers []AnalyticsCount
+	Countries []AnalyticsCount
+	Devices   []AnalyticsCount
+}
+
+// GetAccountAnalytics sums up the visits to every non-deleted url of userId.
+// The grouping is left to the visit repository and the rollups, only the
+// hourly counts are put into buckets here. The query is expected to be valid.
+func (urlS *UrlService) GetAccountAnalytics(
+	userId primitive.ObjectID,
+	query AnalyticsQuery,
+) (AccountAnalytics, error) {
+	urls, err := urlS.UrlRepository.FindByUser(
+		context.TODO(), userId, false, 0, 0,
+	)
+	if err != nil {
+		log.Println(err)
+		return AccountAnalytics{}, fmt.Errorf("internal server error")
+	}
+
+	filter := VisitFilter{
+		From:        query.From,
+		To:          query.To,
+		IncludeBots: query.IncludeBots,
+	}
+	for _, url := range urls {
+		filter.UrlIds = append(filter.UrlIds, url.ID)
+	}
+
+	counts := make(map[string][]VisitCount)
+
+	rolledUp, ok, err := urlS.rolledUpPart(filter)
+	if err != nil {
+		log.Println(err)
+		return AccountAnalytics{}, fmt.Errorf("internal server error")
+	}
+
+	if ok {
+		err = urlS.countRollups(query, rolledUp, counts)
+		if err != nil {
+			log.Println(err)
+			return AccountAnalytics{}, fmt.Errorf("internal server error")
+		}
+	}
+
+	for _, raw := range rawParts(filter, rolledUp, ok) {
+		for _, dimension := range []string{
+			VisitHour, VisitUrl, VisitReferrer, VisitCountry, VisitDevice,
+		} {
+			rawCounts, err := urlS.VisitRepository.CountBy(
+				context.TODO(), raw, dimension,
+			)
+			if err != nil {
+				log.Println(err)
+				return AccountAnalytics{}, fmt.Errorf("internal server error")
+			}
+
+			label := visitLabels[dimension]
+			for _, count := range rawCounts {
+				if label != nil {
+					count.Value = label(count.Value)
+				}
+
+				counts[dimension] = append(counts[dimension], count)
+			}
+		}
+	}
+
+	analytics := AccountAnalytics{
+		Series:    clickSeries(query, counts[VisitHour]),
+		Referrers: topCounts(counts[VisitReferrer], DashboardTopEntries),
+		Countries: topCounts(counts[VisitCountry], DashboardTopEntries),
+		Devices:   topCounts(counts[VisitDevice], 0),
+		TopLinks:  topLinks(urls, counts[VisitUrl]),
+	}
+
+	for _, bucket := range analytics.Series {
+		analytics.Clicks += bucket.Clicks
+	}
+
+	return analytics, nil
+}
+
+// visitLabels turns the values visits are grouped by into the labels the
+// dashboard shows, which rollups are stored with already.
+var visitLabels = map[string]func(string) string{
+	VisitReferrer: referrerDomain,
+	VisitCountry:  orUnknown,
+	VisitDevice:   deviceLabel,
+}
+
+// countRollups adds the rollups of the visits filter selects to counts, by
+// the dimensions CountBy groups visits by.
+func (urlS *UrlService) countRollups(
+	query AnalyticsQuery, filter VisitFilter, counts map[string][]VisitCount,
+) error {
+	for _, dimension := range []string{
+		RollupTotal, RollupReferrer, RollupCountry, RollupDevice,
+	} {
+		rollups, err := urlS.findRollups(query, filter, dimension)
+		if err != nil {
+			return err
+		}
+
+		for _, rollup := range rollups {
+			count := VisitCount{Value: rollup.Value, Count: rollup.Clicks}
+
+			switch dimension {
+			case RollupTotal:
+				counts[VisitHour] = append(counts[VisitHour], VisitCount{
+					Value: rollup.Start.UTC().Format(VisitHourLayout),
+					Count: rollup.Clicks,
+				})
+				counts[VisitUrl] = append(counts[VisitUrl], VisitCount{
+					Value: rollup.UrlId.Hex(),
+					Count: rollup.Clicks,
+				})
+			case RollupReferrer:
+				counts[VisitReferrer] = append(counts[VisitReferrer], count)
+			case RollupCountry:
+				counts[VisitCountry] = append(counts[VisitCountry], count)
+			case RollupDevice:
+				counts[VisitDevice] = append(counts[VisitDevice], count)
+			}
+		}
+	}
+
+	return nil
+}
+
+// clickSeries puts hourly counts into the buckets of query.
+func clickSeries(query AnalyticsQuery, hourly []VisitCount) []ClickBucket {
+	series := []ClickBucket{}
+	buckets := make(map[int64]int)
+
+	start := bucketStart(query.From, query.Interval, query.Location)
+	for ; start.Before(query.To); start = nextBucket(start, query.Interval) {
+		buckets[start.Unix()] = len(series)
+		series = append(series, ClickBucket{Start: start})
+	}
+
+	for _, count := range hourly {
+		hour, err := time.Parse(VisitHourLayout, count.Value)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+
+		// The first hour starts before the range does when the range
+		// starts part way through it, e.g. in timezones half an hour off
+		// UTC. Only its visits made in the range were counted.
+		if hour.Before(query.From) {
+			hour = query.From
+		}
+
+		start := bucketStart(hour, query.Interval, query.Location)
+		if i, ok := buckets[start.Unix()]; ok {
+			series[i].Clicks += count.Count
+		}
+	}
+
+	return series
+}
+
+// topCounts merges counts sharing a value and returns the limit largest, or
+// all of them when limit is 0.
+func topCounts(counts []VisitCount, limit int) []AnalyticsCount {
+	merged := make(map[string]int64)
+	for _, count := range counts {
+		merged[count.Value] += count.Count
+	}
+
+	sorted := sortedCounts(merged)
+	if limit > 0 && len(sorted) > limit {
+		sorted = sorted[:limit]
+	}
+
+	return sorted
+}
+
+func topLinks(urls []Url, counts []VisitCount) []LinkClicks {
+	urlsByID := make(map[string]Url, len(urls))
+	for _, url := range urls {
+		urlsByID[url.ID.Hex()] = url
+	}
+
+	links := []LinkClicks{}
+	for _, count := range topCounts(counts, DashboardTopEntries) {
+		url, ok := urlsByID[count.Value]
+		if !ok {
+			continue
+		}
+
+		links = append(links, LinkClicks{Url: url, Clicks: count.Clicks})
+	}
+
+	return links
+}
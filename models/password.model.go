@@ -0,0 +1,86 @@
+package models
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Origho-precious/url-shortener/go/configs"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MaxUrlPasswordLength is the longest password bcrypt can hash.
+const MaxUrlPasswordLength = 72
+
+func (urlS *UrlService) hashPassword(password string) (string, error) {
+	hashByte, err := bcrypt.GenerateFromPassword(
+		[]byte(password), bcrypt.DefaultCost,
+	)
+
+	return string(hashByte), err
+}
+
+// CheckUrlPassword reports whether password unlocks url.
+func (urlS *UrlService) CheckUrlPassword(url Url, password string) bool {
+	err := bcrypt.CompareHashAndPassword(
+		[]byte(url.PasswordHash), []byte(password),
+	)
+
+	return err == nil
+}
+
+// UnlockToken returns a token proving a visitor entered the password of url,
+// valid until expiresAt. Changing the password invalidates it.
+func (urlS *UrlService) UnlockToken(url Url, expiresAt time.Time) (
+	string, error,
+) {
+	expiry := strconv.FormatInt(expiresAt.Unix(), 10)
+
+	signature, err := unlockSignature(url, expiry)
+	if err != nil {
+		return "", err
+	}
+
+	return expiry + "." + signature, nil
+}
+
+// VerifyUnlockToken reports whether token was issued by UnlockToken for url
+// and has not expired yet.
+func (urlS *UrlService) VerifyUnlockToken(url Url, token string) bool {
+	expiry, signature, found := strings.Cut(token, ".")
+	if !found {
+		return false
+	}
+
+	expiresAt, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil || time.Now().Unix() >= expiresAt {
+		return false
+	}
+
+	expected, err := unlockSignature(url, expiry)
+	if err != nil {
+		return false
+	}
+
+	return hmac.Equal([]byte(signature), []byte(expected))
+}
+
+func unlockSignature(url Url, expiry string) (string, error) {
+	cfg, err := configs.LoadEnvs()
+	if err != nil {
+		return "", err
+	}
+
+	if cfg.JWT_SECRET == "" {
+		return "", fmt.Errorf("JWT_SECRET is not set")
+	}
+
+	mac := hmac.New(sha256.New, []byte(cfg.JWT_SECRET))
+	mac.Write([]byte(url.ID.Hex() + "|" + url.PasswordHash + "|" + expiry))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// Limits of the custom Open Graph fields of a url.
+const (
+	MaxOGTitleLength       = 200
+	MaxOGDescriptionLength = 500
+)
+
+// HasCustomOG reports whether url sets any of its Open Graph fields.
+func HasCustomOG(url Url) bool {
+	return url.OGTitle != "" || url.OGDescription != "" || url.OGImageUrl != ""
+}
+
+func hasPreviewSettings(url Url) bool {
+	return url.Preview || HasCustomOG(url)
+}
+
+// ValidatePreviewSettings checks the Open Graph fields of url before it is
+// saved. OGImageUrl is expected to be normalised already.
+func ValidatePreviewSettings(url Url) error {
+	if utf8.RuneCountInString(url.OGTitle) > MaxOGTitleLength {
+		return fmt.Errorf(
+			"ogTitle must be at most %d characters long", MaxOGTitleLength,
+		)
+	}
+
+	if utf8.RuneCountInString(url.OGDescription) > MaxOGDescriptionLength {
+		return fmt.Errorf(
+			"ogDescription must be at most %d characters long",
+			MaxOGDescriptionLength,
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,220 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrNotFound is returned by repositories when no record matches a query.
+var ErrNotFound = errors.New("record not found")
+
+// ErrDuplicateKey is returned by repositories when a write would break a
+// unique index, e.g. two non-deleted urls sharing a slug.
+var ErrDuplicateKey = errors.New("duplicate key")
+
+// AliasConflictError is returned when a custom alias is already taken by
+// another non-deleted url.
+type AliasConflictError struct {
+	Alias string
+}
+
+func (e *AliasConflictError) Error() string {
+	return "url with this alias already exist"
+}
+
+// UrlUnavailableError is returned for a url that exists but cannot be
+// visited right now, e.g. because it has expired. FallbackUrl is where its
+// owner wants visitors sent instead, if anywhere.
+type UrlUnavailableError struct {
+	Reason      string
+	FallbackUrl string
+}
+
+func (e *UrlUnavailableError) Error() string {
+	return e.Reason
+}
+
+type UrlRepository interface {
+	// Insert stores a new url. It returns ErrDuplicateKey when a non-deleted
+	// url already uses the same slug.
+	Insert(ctx context.Context, url Url) (primitive.ObjectID, error)
+	// FindBySlug returns the non-deleted url with the given slug.
+	FindBySlug(ctx context.Context, slug string) (Url, error)
+	// FindDeletedBySlug returns the most recently trashed url with the given
+	// slug.
+	FindDeletedBySlug(ctx context.Context, slug string) (Url, error)
+	// FindActiveByOriginalUrl returns the user's urls for originalUrl that
+	// are active at now, i.e. not deleted, already activated, not expired
+	// and not out of clicks, most recent first.
+	FindActiveByOriginalUrl(
+		ctx context.Context, userId primitive.ObjectID, originalUrl string,
+		now time.Time,
+	) ([]Url, error)
+	// FindByID returns the url with the given id owned by userId, looking
+	// in the trash when deleted is true.
+	FindByID(
+		ctx context.Context, id primitive.ObjectID, userId primitive.ObjectID,
+		deleted bool,
+	) (Url, error)
+	// Update saves the editable fields of a non-deleted url owned by
+	// url.UserId. Counters such as VisitCount are left untouched. It
+	// returns ErrDuplicateKey when the new slug is already taken.
+	Update(ctx context.Context, url Url) error
+	SetQRCode(
+		ctx context.Context, id primitive.ObjectID, qrCodeImageUrl string,
+		qrCodeFileId string,
+	) error
+	// Delete permanently removes a url.
+	Delete(ctx context.Context, id primitive.ObjectID) error
+	// FindByUser returns the user's urls, newest first, from the trash when
+	// deleted is true.
+	FindByUser(
+		ctx context.Context, userId primitive.ObjectID, deleted bool, skip int64,
+		limit int64,
+	) ([]Url, error)
+	CountByUser(
+		ctx context.Context, userId primitive.ObjectID, deleted bool,
+	) (int64, error)
+	// SoftDelete moves a url to the trash.
+	SoftDelete(
+		ctx context.Context, id primitive.ObjectID, userId primitive.ObjectID,
+		deletedAt time.Time,
+	) error
+	// Restore takes a url out of the trash. It returns ErrDuplicateKey when
+	// its slug has been reused by another url in the meantime.
+	Restore(
+		ctx context.Context, id primitive.ObjectID, userId primitive.ObjectID,
+	) error
+	// FindDeletedBefore returns up to limit trashed urls deleted before
+	// cutoff. Urls trashed before deletion times were recorded are skipped.
+	FindDeletedBefore(
+		ctx context.Context, cutoff time.Time, limit int64,
+	) ([]Url, error)
+	// IncrementVisitCount counts a visit to a non-deleted url, checking
+	// MaxClicks in the same atomic update. It returns ErrNotFound when the
+	// url is gone or has no clicks left.
+	IncrementVisitCount(
+		ctx context.Context, id primitive.ObjectID, visitedAt time.Time,
+	) error
+	// IncrementBotVisitCount counts a visit by a bot to a non-deleted url.
+	IncrementBotVisitCount(ctx context.Context, id primitive.ObjectID) error
+}
+
+type VisitRepository interface {
+	Insert(ctx context.Context, visit Visit) (primitive.ObjectID, error)
+	// CountBy counts the visits matching filter and their distinct visitors
+	// for each value of one of the Visit dimensions, in no particular order.
+	// Visitors are told apart by their visitor hash, then their ip address,
+	// and a visit with neither counts as a visitor of its own.
+	CountBy(
+		ctx context.Context, filter VisitFilter, dimension string,
+	) ([]VisitCount, error)
+	// CountVisitors counts the visits to any url made in [from, to) and
+	// their distinct visitors for each url, bot flag and value of one of
+	// the Visit dimensions, in no particular order. Visitors are told apart
+	// as CountBy does.
+	CountVisitors(
+		ctx context.Context, from time.Time, to time.Time, dimension string,
+	) ([]VisitorCount, error)
+	// FirstVisitedAt returns the time of the oldest visit, or ErrNotFound
+	// when there are none.
+	FirstVisitedAt(ctx context.Context) (time.Time, error)
+	// DeleteBefore removes the visits made before cutoff and returns how
+	// many there were.
+	DeleteBefore(ctx context.Context, cutoff time.Time) (int64, error)
+	DeleteByUrl(ctx context.Context, urlId primitive.ObjectID) error
+}
+
+// VisitRollupRepository keeps the hourly and daily counts visits are rolled
+// up into, and how far rolling up has got.
+type VisitRollupRepository interface {
+	// Upsert stores rollups, replacing those with the same url, period,
+	// start, dimension, value and bot flag.
+	Upsert(ctx context.Context, rollups []VisitRollup) error
+	// Find returns the rollups of period and dimension for the urls of
+	// filter starting in [filter.From, filter.To), leaving out bot visits
+	// unless filter.IncludeBots is set.
+	Find(
+		ctx context.Context, filter VisitFilter, period string, dimension string,
+	) ([]VisitRollup, error)
+	DeleteByUrl(ctx context.Context, urlId primitive.ObjectID) error
+	// RolledUpUntil returns the time visits have been rolled up until, or
+	// the zero time before the first rollup.
+	RolledUpUntil(ctx context.Context) (time.Time, error)
+	SetRolledUpUntil(ctx context.Context, until time.Time) error
+}
+
+// VisitorSaltRepository keeps the daily salts of visitor hashes.
+type VisitorSaltRepository interface {
+	// FindOrInsert returns the salt stored for salt.Day, storing salt first
+	// when the day has none yet.
+	FindOrInsert(ctx context.Context, salt VisitorSalt) (VisitorSalt, error)
+	// DeleteBefore removes the salts of days before day.
+	DeleteBefore(ctx context.Context, day string) error
+}
+
+type UrlRevisionRepository interface {
+	Insert(ctx context.Context, revision UrlRevision) (primitive.ObjectID, error)
+	// FindByUrl returns the revisions of a url, newest first.
+	FindByUrl(ctx context.Context, urlId primitive.ObjectID) ([]UrlRevision, error)
+	FindByID(
+		ctx context.Context, id primitive.ObjectID, urlId primitive.ObjectID,
+	) (UrlRevision, error)
+	DeleteByUrl(ctx context.Context, urlId primitive.ObjectID) error
+}
+
+// CounterRepository keeps named sequences, e.g. the one behind counter and
+// hashid slugs.
+type CounterRepository interface {
+	// Next atomically increments the named counter and returns its new
+	// value. The first call for a name returns 1.
+	Next(ctx context.Context, name string) (uint64, error)
+}
+
+type UserRepository interface {
+	Insert(ctx context.Context, user User) (primitive.ObjectID, error)
+	FindByID(ctx context.Context, id primitive.ObjectID) (User, error)
+	FindByEmail(ctx context.Context, email string) (User, error)
+	SetEmailVerified(ctx context.Context, id primitive.ObjectID) (User, error)
+	// UpdatePassword stores a new password hash for the user with the given
+	// email and marks their email as verified.
+	UpdatePassword(ctx context.Context, email string, passwordHash string) error
+	UpdateFullName(
+		ctx context.Context, id primitive.ObjectID, fullName string,
+	) (User, error)
+	UpdateFallbackUrl(
+		ctx context.Context, id primitive.ObjectID, fallbackUrl string,
+	) (User, error)
+}
+
+type ForgotPasswordRepository interface {
+	Insert(ctx context.Context, record ForgotPassword) (primitive.ObjectID, error)
+	FindByID(ctx context.Context, id primitive.ObjectID) (ForgotPassword, error)
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+type VerificationTokenRepository interface {
+	Insert(ctx context.Context, record VerificationToken) (primitive.ObjectID, error)
+	FindByUser(ctx context.Context, userId primitive.ObjectID) (VerificationToken, error)
+	Find(
+		ctx context.Context, userId primitive.ObjectID, token string,
+	) (VerificationToken, error)
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+// Repositories groups every repository the routers need, so a storage
+// backend can be swapped without touching the services.
+type Repositories struct {
+	Urls               UrlRepository
+	Visits             VisitRepository
+	VisitRollups       VisitRollupRepository
+	VisitorSalts       VisitorSaltRepository
+	UrlRevisions       UrlRevisionRepository
+	Counters           CounterRepository
+	Users              UserRepository
+	ForgotPasswords    ForgotPasswordRepository
+	VerificationTokens VerificationTokenRepository
+}
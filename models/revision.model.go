@@ -0,0 +1,102 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UrlRevision records one change to the destination or expiry of a url.
+type UrlRevision struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty"`
+	UrlId          primitive.ObjectID
+	ChangedBy      primitive.ObjectID
+	ChangedAt      time.Time
+	OldOriginalUrl string
+	NewOriginalUrl string
+	OldExpiresAt   time.Time
+	NewExpiresAt   time.Time
+}
+
+// recordRevision stores the change from before to after made by changedBy,
+// if the destination or expiry changed. A failure is logged rather than returned since the url
+// itself has already been saved.
+func (urlS *UrlService) recordRevision(
+	before Url,
+	after Url,
+	changedBy primitive.ObjectID,
+) {
+	if before.OriginalUrl == after.OriginalUrl &&
+		before.ExpiresAt.Equal(after.ExpiresAt) {
+		return
+	}
+
+	_, err := urlS.RevisionRepository.Insert(context.TODO(), UrlRevision{
+		UrlId:          after.ID,
+		ChangedBy:      changedBy,
+		ChangedAt:      time.Now(),
+		OldOriginalUrl: before.OriginalUrl,
+		NewOriginalUrl: after.OriginalUrl,
+		OldExpiresAt:   before.ExpiresAt,
+		NewExpiresAt:   after.ExpiresAt,
+	})
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// GetUrlHistory returns the revisions of the url identified by urlId,
+// newest first, provided it belongs to userId.
+func (urlS *UrlService) GetUrlHistory(
+	urlId primitive.ObjectID,
+	userId primitive.ObjectID,
+) ([]UrlRevision, error) {
+	_, err := urlS.UrlRepository.FindByID(
+		context.TODO(), urlId, userId, false,
+	)
+	if err != nil {
+		if err == ErrNotFound {
+			return nil, fmt.Errorf("no matching document found")
+		}
+
+		log.Println(err)
+		return nil, fmt.Errorf("internal server error")
+	}
+
+	revisions, err := urlS.RevisionRepository.FindByUrl(context.TODO(), urlId)
+	if err != nil {
+		log.Println(err)
+		return nil, fmt.Errorf("internal server error")
+	}
+
+	return revisions, nil
+}
+
+// RollbackUrl undoes a revision by restoring the destination and expiry the
+// url identified by urlId had before it, provided the url belongs to userId.
+// The rollback is itself recorded as a new revision.
+func (urlS *UrlService) RollbackUrl(
+	urlId primitive.ObjectID,
+	userId primitive.ObjectID,
+	revisionId primitive.ObjectID,
+) (Url, error) {
+	revision, err := urlS.RevisionRepository.FindByID(
+		context.TODO(), revisionId, urlId,
+	)
+	if err != nil {
+		if err == ErrNotFound {
+			return Url{}, fmt.Errorf("no matching revision found")
+		}
+
+		log.Println(err)
+		return Url{}, fmt.Errorf("internal server error")
+	}
+
+	return urlS.UpdateUrl(urlId, userId, UrlUpdate{
+		OriginalUrl: &revision.OldOriginalUrl,
+		ExpiresAt:   &revision.OldExpiresAt,
+	})
+}
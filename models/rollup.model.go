@@ -0,0 +1,323 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Periods visits are rolled up by. Days are UTC days.
+const (
+	RollupHour = "hour"
+	RollupDay  = "day"
+)
+
+// Dimensions visits are rolled up by. RollupTotal counts every visit, the
+// others count the visits per browser, referrer domain etc.
+const (
+	RollupTotal    = "total"
+	RollupBrowser  = "browser"
+	RollupDevice   = "device"
+	RollupReferrer = "referrer"
+	RollupCountry  = "country"
+	RollupLocation = "location"
+)
+
+var rollupDimensions = []string{
+	RollupTotal, RollupBrowser, RollupDevice, RollupReferrer, RollupCountry,
+	RollupLocation,
+}
+
+// VisitRollup counts the visits to a url in the hour or day starting at
+// Start that share the Value of a dimension. Bot and human visits are
+// rolled up separately.
+type VisitRollup struct {
+	UrlId          primitive.ObjectID
+	Period         string
+	Start          time.Time
+	Dimension      string
+	Value          string
+	Bot            bool
+	Clicks         int64
+	UniqueVisitors int64
+}
+
+// RollUpVisits rolls up the visits of every hour before until that has not
+// been rolled up yet, along with the days completed on the way. The first
+// run starts from the oldest visit.
+func (urlS *UrlService) RollUpVisits(until time.Time) error {
+	ctx := context.TODO()
+	until = until.UTC().Truncate(time.Hour)
+
+	from, err := urlS.RollupRepository.RolledUpUntil(ctx)
+	if err != nil {
+		return err
+	}
+
+	if from.IsZero() {
+		first, err := urlS.VisitRepository.FirstVisitedAt(ctx)
+		if err == ErrNotFound {
+			return urlS.RollupRepository.SetRolledUpUntil(ctx, until)
+		}
+		if err != nil {
+			return err
+		}
+
+		from = first.UTC().Truncate(time.Hour)
+	}
+
+	for hour := from.UTC(); hour.Before(until); hour = hour.Add(time.Hour) {
+		next := hour.Add(time.Hour)
+
+		err = urlS.rollUp(RollupHour, hour, next)
+		if err != nil {
+			return err
+		}
+
+		if next.Hour() == 0 {
+			err = urlS.rollUp(RollupDay, next.AddDate(0, 0, -1), next)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = urlS.RollupRepository.SetRolledUpUntil(ctx, next)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeleteOldVisits deletes the raw visits made before cutoff. Visits of the
+// UTC day rolling up is at are kept for its daily rollup. Analytics read raw
+// visits for the hours a range starts or ends part way through, so those
+// hours count no visits anymore before cutoff.
+func (urlS *UrlService) DeleteOldVisits(cutoff time.Time) (int64, error) {
+	rolledUpUntil, err := urlS.RollupRepository.RolledUpUntil(context.TODO())
+	if err != nil {
+		return 0, err
+	}
+
+	rolledUpDay := rolledUpUntil.UTC().Truncate(24 * time.Hour)
+	if rolledUpDay.Before(cutoff) {
+		cutoff = rolledUpDay
+	}
+
+	return urlS.VisitRepository.DeleteBefore(context.TODO(), cutoff)
+}
+
+// VisitorCount is the number of visits to a url sharing a value of a
+// dimension and a bot flag, and how many distinct visitors made them.
+type VisitorCount struct {
+	UrlId    primitive.ObjectID
+	Bot      bool
+	Value    string
+	Clicks   int64
+	Visitors int64
+}
+
+// rollupVisitDimensions are the dimensions of VisitRepository.CountVisitors
+// each rollup dimension is counted by. Visits are always grouped by url, so
+// grouping them by url too counts their total.
+var rollupVisitDimensions = map[string]string{
+	RollupTotal:    VisitUrl,
+	RollupBrowser:  VisitBrowser,
+	RollupDevice:   VisitDevice,
+	RollupReferrer: VisitReferrer,
+	RollupCountry:  VisitCountry,
+	RollupLocation: VisitLocation,
+}
+
+// rollUp stores the rollups of the visits made in [start, end). The visits
+// are grouped by the visit repository.
+func (urlS *UrlService) rollUp(period string, start time.Time, end time.Time) error {
+	var rollups []VisitRollup
+
+	for _, dimension := range rollupDimensions {
+		counts, err := urlS.VisitRepository.CountVisitors(
+			context.TODO(), start, end, rollupVisitDimensions[dimension],
+		)
+		if err != nil {
+			return err
+		}
+
+		rollups = append(
+			rollups, rollUpCounts(counts, period, start, dimension)...,
+		)
+	}
+
+	if len(rollups) == 0 {
+		return nil
+	}
+
+	return urlS.RollupRepository.Upsert(context.TODO(), rollups)
+}
+
+// rollUpCounts turns counts into the rollups of dimension. Values sharing a
+// label are merged, e.g. referrers of the same domain, and their visitors
+// summed.
+func rollUpCounts(
+	counts []VisitorCount, period string, start time.Time, dimension string,
+) []VisitRollup {
+	type rollupKey struct {
+		urlId primitive.ObjectID
+		value string
+		bot   bool
+	}
+
+	var keys []rollupKey
+	merged := make(map[rollupKey]VisitRollup)
+
+	for _, count := range counts {
+		key := rollupKey{
+			urlId: count.UrlId,
+			value: rollupLabel(dimension, count.Value),
+			bot:   count.Bot,
+		}
+
+		rollup, ok := merged[key]
+		if !ok {
+			keys = append(keys, key)
+			rollup = VisitRollup{
+				UrlId:     key.urlId,
+				Period:    period,
+				Start:     start,
+				Dimension: dimension,
+				Value:     key.value,
+				Bot:       key.bot,
+			}
+		}
+
+		rollup.Clicks += count.Clicks
+		rollup.UniqueVisitors += count.Visitors
+		merged[key] = rollup
+	}
+
+	rollups := make([]VisitRollup, 0, len(keys))
+	for _, key := range keys {
+		rollups = append(rollups, merged[key])
+	}
+
+	return rollups
+}
+
+// rollupLabel returns the value of dimension labelled the way analytics
+// show it.
+func rollupLabel(dimension string, value string) string {
+	switch dimension {
+	case RollupBrowser, RollupCountry, RollupLocation:
+		return orUnknown(value)
+	case RollupDevice:
+		return deviceLabel(value)
+	case RollupReferrer:
+		return referrerDomain(value)
+	default:
+		return ""
+	}
+}
+
+// rolledUpPart returns the part of filter's range that rollups cover, or
+// false when they cover none of it. Rollups count whole hours, so the part
+// only runs from the first to the last whole hour of the range. The visits
+// of the hours the range starts or ends part way through are read raw, see
+// rawParts.
+func (urlS *UrlService) rolledUpPart(filter VisitFilter) (VisitFilter, bool, error) {
+	rolledUpUntil, err := urlS.RollupRepository.RolledUpUntil(context.TODO())
+	if err != nil {
+		return VisitFilter{}, false, err
+	}
+
+	from := filter.From.UTC().Truncate(time.Hour)
+	if from.Before(filter.From) {
+		from = from.Add(time.Hour)
+	}
+
+	to := filter.To.UTC().Truncate(time.Hour)
+	if rolledUpUntil.Before(to) {
+		to = rolledUpUntil
+	}
+
+	if !from.Before(to) {
+		return VisitFilter{}, false, nil
+	}
+
+	filter.From, filter.To = from, to
+
+	return filter, true, nil
+}
+
+// rawParts returns the parts of filter's range rolledUp, as returned by
+// rolledUpPart, leaves to raw visits.
+func rawParts(filter VisitFilter, rolledUp VisitFilter, ok bool) []VisitFilter {
+	if !ok {
+		return []VisitFilter{filter}
+	}
+
+	var parts []VisitFilter
+	for _, part := range [][2]time.Time{
+		{filter.From, rolledUp.From},
+		{rolledUp.To, filter.To},
+	} {
+		if part[0].Before(part[1]) {
+			raw := filter
+			raw.From, raw.To = part[0], part[1]
+			parts = append(parts, raw)
+		}
+	}
+
+	return parts
+}
+
+// findRollups returns the rollups of dimension for the visits filter selects,
+// which must start and end on an hour. Daily rollups are used for whole days
+// when the buckets of query are UTC days, weeks or months, hourly ones
+// otherwise.
+func (urlS *UrlService) findRollups(
+	query AnalyticsQuery, filter VisitFilter, dimension string,
+) ([]VisitRollup, error) {
+	filter.From = filter.From.UTC()
+
+	firstDay := filter.From.Truncate(24 * time.Hour)
+	if firstDay.Before(filter.From) {
+		firstDay = firstDay.AddDate(0, 0, 1)
+	}
+	lastDay := filter.To.UTC().Truncate(24 * time.Hour)
+
+	if query.Interval == AnalyticsHour || query.Location.String() != "UTC" ||
+		!firstDay.Before(lastDay) {
+		return urlS.RollupRepository.Find(
+			context.TODO(), filter, RollupHour, dimension,
+		)
+	}
+
+	var rollups []VisitRollup
+	for _, part := range []struct {
+		period   string
+		from, to time.Time
+	}{
+		{RollupHour, filter.From, firstDay},
+		{RollupDay, firstDay, lastDay},
+		{RollupHour, lastDay, filter.To},
+	} {
+		if !part.from.Before(part.to) {
+			continue
+		}
+
+		partFilter := filter
+		partFilter.From, partFilter.To = part.from, part.to
+
+		found, err := urlS.RollupRepository.Find(
+			context.TODO(), partFilter, part.period, dimension,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		rollups = append(rollups, found...)
+	}
+
+	return rollups, nil
+}
@@ -0,0 +1,177 @@
+package models_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Origho-precious/url-shortener/go/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TestAnalyticsMatchAfterRollup checks that rolling visits up leaves the
+// analytics of ranges that start and end part way through an hour as they
+// were. Every visit has a visitor of its own, so the unique visitors summed
+// over rollups match too.
+func TestAnalyticsMatchAfterRollup(t *testing.T) {
+	at := func(value string) time.Time {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return parsed
+	}
+
+	visits := []models.Visit{
+		{VisitedAt: at("2026-03-02T10:10:00Z"), Browser: "Chrome",
+			Referrer: "https://www.google.com/search"},
+		{VisitedAt: at("2026-03-02T10:40:00Z"), Browser: "Firefox",
+			DeviceType: "mobile", Referrer: "https://google.com/"},
+		{VisitedAt: at("2026-03-02T11:20:00Z"), Browser: "Googlebot",
+			DeviceType: "bot", Bot: true},
+		{VisitedAt: at("2026-03-02T11:55:00Z"), Browser: "Safari",
+			Country: "NG", Location: "Lagos, Nigeria"},
+		{VisitedAt: at("2026-03-02T12:10:00Z"), Browser: "Chrome"},
+		{VisitedAt: at("2026-03-02T23:30:00Z"), Browser: "Edge",
+			Referrer: "https://news.ycombinator.com/item"},
+		{VisitedAt: at("2026-03-03T05:00:00Z"), Browser: "Chrome",
+			DeviceType: "tablet"},
+		{VisitedAt: at("2026-03-03T14:15:00Z"), IPAddress: "203.0.113.7"},
+		{VisitedAt: at("2026-03-04T09:05:00Z"), Browser: "Safari",
+			Country: "GB", Location: "London, United Kingdom"},
+		{VisitedAt: at("2026-03-04T09:50:00Z"), Browser: "Firefox"},
+	}
+
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	queries := map[string]models.AnalyticsQuery{
+		"part hours": {
+			From:     at("2026-03-02T10:30:00Z"),
+			To:       at("2026-03-02T12:00:00Z"),
+			Interval: models.AnalyticsHour,
+			Location: time.UTC,
+		},
+		"part hours at both ends": {
+			From:     at("2026-03-02T10:30:00Z"),
+			To:       at("2026-03-02T12:05:00Z"),
+			Interval: models.AnalyticsHour,
+			Location: time.UTC,
+		},
+		"days": {
+			From:     at("2026-03-02T10:30:00Z"),
+			To:       at("2026-03-04T09:20:00Z"),
+			Interval: models.AnalyticsDay,
+			Location: time.UTC,
+		},
+		"days with bots": {
+			From:        at("2026-03-02T10:30:00Z"),
+			To:          at("2026-03-04T09:20:00Z"),
+			Interval:    models.AnalyticsDay,
+			Location:    time.UTC,
+			IncludeBots: true,
+		},
+		"hours in another timezone": {
+			From:     at("2026-03-02T10:45:00Z"),
+			To:       at("2026-03-03T14:20:00Z"),
+			Interval: models.AnalyticsHour,
+			Location: newYork,
+		},
+	}
+
+	for name, repos := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			userId := primitive.NewObjectID()
+
+			urlId, err := repos.Urls.Insert(ctx, models.Url{
+				UserId:       userId,
+				OriginalUrl:  "https://example.com",
+				ShortUrlSlug: "rollup",
+				CreatedAt:    at("2026-03-01T00:00:00Z"),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i, visit := range visits {
+				visit.UrlId = urlId
+				if visit.IPAddress == "" {
+					visit.VisitorHash = primitive.NewObjectID().Hex()
+				}
+
+				if _, err = repos.Visits.Insert(ctx, visit); err != nil {
+					t.Fatalf("visit %d: %v", i, err)
+				}
+			}
+
+			analytics := func(query models.AnalyticsQuery) (
+				models.Analytics, models.AccountAnalytics,
+			) {
+				t.Helper()
+
+				urlService := newTestUrlService(repos)
+
+				url, err := urlService.GetUrlAnalytics(urlId, userId, query)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				account, err := urlService.GetAccountAnalytics(userId, query)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				return url, account
+			}
+
+			type result struct {
+				url     models.Analytics
+				account models.AccountAnalytics
+			}
+
+			raw := make(map[string]result)
+			for queryName, query := range queries {
+				url, account := analytics(query)
+				raw[queryName] = result{url, account}
+			}
+
+			if clicks := raw["part hours"].url.Clicks; clicks != 2 {
+				t.Fatalf("got %d clicks from 10:30 to 12:00, want 2", clicks)
+			}
+
+			// Rolling up part of the range first leaves raw visits on both
+			// sides of the rollups.
+			for _, until := range []string{
+				"2026-03-03T12:00:00Z", "2026-03-05T00:00:00Z",
+			} {
+				urlService := newTestUrlService(repos)
+				if err = urlService.RollUpVisits(at(until)); err != nil {
+					t.Fatal(err)
+				}
+
+				for queryName, query := range queries {
+					url, account := analytics(query)
+
+					if !reflect.DeepEqual(url, raw[queryName].url) {
+						t.Errorf(
+							"%s: url analytics rolled up until %s\ngot  %+v\nwant %+v",
+							queryName, until, url, raw[queryName].url,
+						)
+					}
+
+					if !reflect.DeepEqual(account, raw[queryName].account) {
+						t.Errorf(
+							"%s: account analytics rolled up until %s\ngot  %+v\nwant %+v",
+							queryName, until, account, raw[queryName].account,
+						)
+					}
+				}
+			}
+		})
+	}
+}
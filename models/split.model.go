@@ -0,0 +1,110 @@
+package models
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// A url splitting its traffic needs between MinSplitDestinations and
+// MaxSplitDestinations destinations.
+const (
+	MinSplitDestinations = 2
+	MaxSplitDestinations = 10
+	MaxSplitWeight       = 1000
+)
+
+// SplitDestination is one variant of a url that rotates its visitors between
+// several destinations. A variant gets Weight out of the summed weights of
+// all variants.
+type SplitDestination struct {
+	// Variant names the destination in analytics, it defaults to A, B, ...
+	Variant string `json:"variant"`
+	Url     string `json:"url"`
+	Weight  int    `json:"weight"`
+}
+
+// Destination is where a visitor ends up. Variant is set when a split
+// destination was picked.
+type Destination struct {
+	Url     string
+	Variant string
+}
+
+// NameSplitVariants fills in missing variant names with the letter of their
+// position.
+func NameSplitVariants(destinations []SplitDestination) []SplitDestination {
+	for i := range destinations {
+		if destinations[i].Variant == "" && i < 26 {
+			destinations[i].Variant = string(rune('A' + i))
+		}
+	}
+
+	return destinations
+}
+
+// ValidateSplitDestinations checks split destinations before they are saved.
+// Their urls are expected to be normalised already.
+func ValidateSplitDestinations(destinations []SplitDestination) error {
+	if len(destinations) == 0 {
+		return nil
+	}
+
+	if len(destinations) < MinSplitDestinations ||
+		len(destinations) > MaxSplitDestinations {
+		return fmt.Errorf(
+			"a url needs between %d and %d split destinations",
+			MinSplitDestinations, MaxSplitDestinations,
+		)
+	}
+
+	variants := make(map[string]bool)
+	for i, destination := range destinations {
+		if destination.Url == "" {
+			return fmt.Errorf("split destination %d needs a url", i+1)
+		}
+
+		if destination.Weight < 1 || destination.Weight > MaxSplitWeight {
+			return fmt.Errorf(
+				"split destination %d: weight must be between 1 and %d",
+				i+1, MaxSplitWeight,
+			)
+		}
+
+		if variants[destination.Variant] {
+			return fmt.Errorf(
+				"split destination %d: variant %q is used more than once",
+				i+1, destination.Variant,
+			)
+		}
+		variants[destination.Variant] = true
+	}
+
+	return nil
+}
+
+// pickSplitDestination returns the destination named by stickyVariant if
+// there is one, so returning visitors keep seeing the same variant, and
+// otherwise draws one by weight.
+func pickSplitDestination(
+	destinations []SplitDestination, stickyVariant string,
+) SplitDestination {
+	total := 0
+	for _, destination := range destinations {
+		if stickyVariant != "" && destination.Variant == stickyVariant {
+			return destination
+		}
+
+		total += destination.Weight
+	}
+
+	n := rand.Intn(total)
+	for _, destination := range destinations {
+		if n < destination.Weight {
+			return destination
+		}
+
+		n -= destination.Weight
+	}
+
+	return destinations[len(destinations)-1]
+}
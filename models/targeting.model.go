@@ -0,0 +1,124 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// MaxTargetingRules bounds how many targeting rules a url may carry.
+const MaxTargetingRules = 20
+
+// Values TargetingRule.OS and TargetingRule.Device may take.
+var (
+	TargetingOSes    = []string{"ios", "android", "windows", "macos", "linux"}
+	TargetingDevices = []string{"mobile", "tablet", "desktop"}
+)
+
+var countryCode = regexp.MustCompile(`^[A-Z]{2}$`)
+
+// TargetingRule sends visitors matching all of its set conditions to Url
+// instead of the url's OriginalUrl.
+type TargetingRule struct {
+	OS     string `json:"os,omitempty"`
+	Device string `json:"device,omitempty"`
+	// Country is an ISO 3166-1 alpha-2 code, it never matches visitors that
+	// could not be located.
+	Country string `json:"country,omitempty"`
+	Url     string `json:"url"`
+}
+
+// Visitor describes who is following a short url, as far as targeting rules
+// and split destinations are concerned.
+type Visitor struct {
+	OS      string
+	Device  string
+	Country string
+	// StickyVariant is the split variant the visitor was sent to before.
+	StickyVariant string
+}
+
+func (rule TargetingRule) matches(visitor Visitor) bool {
+	if rule.OS != "" && rule.OS != visitor.OS {
+		return false
+	}
+
+	if rule.Device != "" && rule.Device != visitor.Device {
+		return false
+	}
+
+	if rule.Country != "" && rule.Country != visitor.Country {
+		return false
+	}
+
+	return true
+}
+
+// ValidateTargetingRules checks rules before they are saved. Their urls are
+// expected to be normalised already.
+func ValidateTargetingRules(rules []TargetingRule) error {
+	if len(rules) > MaxTargetingRules {
+		return fmt.Errorf(
+			"a url can have at most %d targeting rules", MaxTargetingRules,
+		)
+	}
+
+	for i, rule := range rules {
+		if rule.OS == "" && rule.Device == "" && rule.Country == "" {
+			return fmt.Errorf(
+				"targeting rule %d needs an os, a device or a country", i+1,
+			)
+		}
+
+		if rule.OS != "" && !slices.Contains(TargetingOSes, rule.OS) {
+			return fmt.Errorf(
+				"targeting rule %d: os must be one of: %s",
+				i+1, strings.Join(TargetingOSes, ", "),
+			)
+		}
+
+		if rule.Device != "" && !slices.Contains(TargetingDevices, rule.Device) {
+			return fmt.Errorf(
+				"targeting rule %d: device must be one of: %s",
+				i+1, strings.Join(TargetingDevices, ", "),
+			)
+		}
+
+		if rule.Country != "" && !countryCode.MatchString(rule.Country) {
+			return fmt.Errorf(
+				"targeting rule %d: country must be a two letter ISO 3166-1 code",
+				i+1,
+			)
+		}
+
+		if rule.Url == "" {
+			return fmt.Errorf("targeting rule %d needs a url", i+1)
+		}
+	}
+
+	return nil
+}
+
+// ResolveDestination returns the url of the first targeting rule of url that
+// matches visitor. When none does the visitor goes to one of the split
+// destinations of url, or to its OriginalUrl if it has none.
+func (urlS *UrlService) ResolveDestination(
+	url Url, visitor Visitor,
+) Destination {
+	for _, rule := range url.TargetingRules {
+		if rule.matches(visitor) {
+			return Destination{Url: rule.Url}
+		}
+	}
+
+	if len(url.SplitDestinations) > 0 {
+		split := pickSplitDestination(
+			url.SplitDestinations, visitor.StickyVariant,
+		)
+
+		return Destination{Url: split.Url, Variant: split.Variant}
+	}
+
+	return Destination{Url: url.OriginalUrl}
+}
@@ -0,0 +1,130 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// trashPurgeBatchSize bounds how many urls are loaded per purge query.
+const trashPurgeBatchSize = 100
+
+func (urlS *UrlService) GetTrashedUrls(
+	userId primitive.ObjectID,
+	page int,
+	limit int,
+) ([]Url, int64, error) {
+	return urlS.urlsByUser(userId, true, page, limit)
+}
+
+// RestoreUrl takes the url identified by urlId out of the trash, provided
+// it belongs to userId, and returns it.
+func (urlS *UrlService) RestoreUrl(
+	urlId primitive.ObjectID,
+	userId primitive.ObjectID,
+) (Url, error) {
+	err := urlS.UrlRepository.Restore(context.TODO(), urlId, userId)
+	if err != nil {
+		if err == ErrNotFound {
+			return Url{}, fmt.Errorf("no matching document found in trash")
+		} else if err == ErrDuplicateKey {
+			return Url{}, fmt.Errorf("the slug of this url is now used by another url")
+		}
+
+		log.Println(err)
+		return Url{}, fmt.Errorf("internal server error")
+	}
+
+	url, err := urlS.UrlRepository.FindByID(context.TODO(), urlId, userId, false)
+	if err != nil {
+		log.Println(err)
+		return Url{}, fmt.Errorf("internal server error")
+	}
+
+	return url, nil
+}
+
+// PurgeUrl permanently deletes the trashed url identified by urlId,
+// provided it belongs to userId.
+func (urlS *UrlService) PurgeUrl(
+	urlId primitive.ObjectID,
+	userId primitive.ObjectID,
+) error {
+	url, err := urlS.UrlRepository.FindByID(context.TODO(), urlId, userId, true)
+	if err != nil {
+		if err == ErrNotFound {
+			return fmt.Errorf("no matching document found in trash")
+		}
+
+		log.Println(err)
+		return fmt.Errorf("internal server error")
+	}
+
+	err = urlS.purge(url)
+	if err != nil {
+		log.Println(err)
+		return fmt.Errorf("internal server error")
+	}
+
+	return nil
+}
+
+// PurgeExpiredTrash permanently deletes every url trashed before cutoff and
+// returns how many were removed.
+func (urlS *UrlService) PurgeExpiredTrash(cutoff time.Time) (int, error) {
+	purged := 0
+
+	for {
+		urls, err := urlS.UrlRepository.FindDeletedBefore(
+			context.TODO(), cutoff, trashPurgeBatchSize,
+		)
+		if err != nil {
+			return purged, err
+		}
+
+		if len(urls) == 0 {
+			return purged, nil
+		}
+
+		for _, url := range urls {
+			err = urlS.purge(url)
+			if err != nil {
+				return purged, err
+			}
+
+			purged++
+		}
+	}
+}
+
+// purge removes a url together with its visits, rollups, revisions and QR
+// code. The url record goes last so an interrupted purge can simply be
+// retried.
+func (urlS *UrlService) purge(url Url) error {
+	err := urlS.VisitRepository.DeleteByUrl(context.TODO(), url.ID)
+	if err != nil {
+		return err
+	}
+
+	err = urlS.RollupRepository.DeleteByUrl(context.TODO(), url.ID)
+	if err != nil {
+		return err
+	}
+
+	err = urlS.RevisionRepository.DeleteByUrl(context.TODO(), url.ID)
+	if err != nil {
+		return err
+	}
+
+	urlS.deleteQRCode(url.QRCodeFileId)
+
+	err = urlS.UrlRepository.Delete(context.TODO(), url.ID)
+	if err != nil && err != ErrNotFound {
+		return err
+	}
+
+	return nil
+}
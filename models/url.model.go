@@ -3,35 +3,79 @@ package models
 import (
 	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"log"
+	"strconv"
 	"time"
 
 	"github.com/Origho-precious/url-shortener/go/configs"
 	"github.com/Origho-precious/url-shortener/go/services"
+	"github.com/Origho-precious/url-shortener/go/utils"
 	"github.com/imagekit-developer/imagekit-go"
 	"github.com/imagekit-developer/imagekit-go/api/uploader"
 	"github.com/skip2/go-qrcode"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type Url struct {
 	ID             primitive.ObjectID `bson:"_id,omitempty"`
 	UserId         primitive.ObjectID `bson:"userId,omitempty"`
 	Deleted        bool
+	DeletedAt      time.Time
 	CreatedAt      time.Time
 	ExpiresAt      time.Time
+	ActivatesAt    time.Time
 	VisitCount     int64
+	BotVisitCount  int64
 	CustomAlias    bool
 	OriginalUrl    string
 	ShortUrlSlug   string
 	LastVisitedAt  time.Time
 	QRCodeImageUrl string
+	QRCodeFileId   string
+	// PasswordHash is the bcrypt hash visitors' passwords are checked
+	// against. Urls without one redirect straight away.
+	PasswordHash string
+	// MaxClicks is the number of visits after which the url expires, 0
+	// means no limit.
+	MaxClicks int64
+	// Visitors of a url that has expired or is not active yet are sent to
+	// these urls when they are set, otherwise to FallbackUrl, which also
+	// covers deleted urls, and then to the owner's account fallback.
+	ExpiredRedirectUrl  string
+	InactiveRedirectUrl string
+	FallbackUrl         string
+	// TargetingRules are tried in order before falling back to OriginalUrl.
+	TargetingRules []TargetingRule
+	// SplitDestinations replace OriginalUrl for visitors no targeting rule
+	// matched. StickySplit keeps sending a visitor to the same variant.
+	SplitDestinations []SplitDestination
+	StickySplit       bool
+	// The Utm parameters are added to every destination of the url.
+	UtmSource   string
+	UtmMedium   string
+	UtmCampaign string
+	UtmTerm     string
+	UtmContent  string
+	// QueryPassthrough is one of the QueryPassthrough modes.
+	QueryPassthrough string
+	// Preview shows visitors a page with the destination before sending
+	// them on. The OG fields customise that page and how links unfurl.
+	Preview       bool
+	OGTitle       string
+	OGDescription string
+	OGImageUrl    string
 }
 
+// Reasons a url cannot be visited, as reported by GetOriginalUrl.
+const (
+	UrlUnknownReason  = "invalid shorturl"
+	UrlExpiredReason  = "url has expired"
+	UrlDeletedReason  = "url has been deleted"
+	UrlInactiveReason = "url is not yet active"
+)
+
 type Visit struct {
 	ID         primitive.ObjectID `bson:"_id,omitempty"`
 	UrlId      primitive.ObjectID
@@ -41,40 +85,137 @@ type Visit struct {
 	IPAddress  string
 	VisitedAt  time.Time
 	DeviceType string
+	// BrowserVersion, OS, OSVersion and Vendor are parsed from the user
+	// agent along with Browser and DeviceType, which is one of mobile,
+	// tablet, desktop or bot. Vendor is the maker of the device.
+	BrowserVersion string
+	OS             string
+	OSVersion      string
+	Vendor         string
+	// VisitorHash tells visits by the same person on the same day apart
+	// from visits by others, see VisitorHasher.
+	VisitorHash string
+	// Country is the ISO 3166-1 alpha-2 code Location is in, if known.
+	Country string
+	// Variant is the split destination the visitor was sent to.
+	Variant string
+	// Bot is set for crawlers, monitors and scripts, which are left out of
+	// Url.VisitCount.
+	Bot bool
 }
 
 type UrlService struct {
-	Url             Url
-	Visit           Visit
-	UrlCollection   *mongo.Collection
-	VisitCollection *mongo.Collection
+	Url               Url
+	UrlRepository     UrlRepository
+	VisitRepository   VisitRepository
+	CounterRepository CounterRepository
+	// RevisionRepository stores the edit history of urls.
+	RevisionRepository UrlRevisionRepository
+	// UserRepository looks up the account fallback of url owners.
+	UserRepository UserRepository
+	// RollupRepository holds the rolled up visits analytics read for
+	// ranges whose raw visits may be gone.
+	RollupRepository VisitRollupRepository
+	// QRCodes keeps the QR codes of urls, ImageKit when it is nil.
+	QRCodes QRCodeStore
+	// GeoIP locates visitors, it is nil when no database is configured.
+	GeoIP *services.GeoIP
+	// VisitorHasher identifies visitors for unique visitor counts.
+	VisitorHasher *VisitorHasher
+}
+
+// slugSequence backs the counter and hashid slug strategies with a counter
+// kept in storage, so slugs keep increasing across restarts.
+type slugSequence struct {
+	counters CounterRepository
 }
 
-func (urlS *UrlService) getShortUrlSlug(alias string) error {
-	if alias == "" {
-		urlSlug := services.GenerateShortURL(urlS.Url.OriginalUrl)
-		urlS.Url.ShortUrlSlug = urlSlug
-		urlS.Url.CustomAlias = false
+func (s slugSequence) Next() (uint64, error) {
+	return s.counters.Next(context.TODO(), "urlSlug")
+}
+
+// slugGenerator returns the generator for strategy, falling back to the
+// SLUG_STRATEGY env when strategy is empty.
+func (urlS *UrlService) slugGenerator(strategy string) (
+	services.SlugGenerator, error,
+) {
+	cfg, err := configs.LoadEnvs()
+	if err != nil {
+		return nil, err
+	}
 
-		return nil
+	if strategy == "" {
+		strategy = cfg.SLUG_STRATEGY
 	}
 
-	var urlRecord Url
+	length := 0
+	if cfg.SLUG_LENGTH != "" {
+		length, err = strconv.Atoi(cfg.SLUG_LENGTH)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SLUG_LENGTH: %w", err)
+		}
+	}
 
-	filter := bson.M{"shortUrlSlug": alias}
-	err := urlS.UrlCollection.FindOne(context.TODO(), filter).Decode(&urlRecord)
-	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			urlS.Url.ShortUrlSlug = alias
-			urlS.Url.CustomAlias = true
+	return services.NewSlugGenerator(services.SlugGeneratorConfig{
+		Strategy: strategy,
+		Length:   length,
+		Salt:     cfg.SLUG_SALT,
+		Sequence: slugSequence{counters: urlS.CounterRepository},
+	})
+}
 
-			return nil
+// maxSlugAttempts bounds how many generated slugs are tried before giving up
+// when every one of them collides with an existing url.
+const maxSlugAttempts = 5
+
+// insertWithUniqueSlug stores url under the given alias, or under a slug
+// from generator when alias is empty, and returns it as stored. Uniqueness
+// is enforced by the storage backend, so two concurrent requests can never
+// end up with the same slug.
+func (urlS *UrlService) insertWithUniqueSlug(
+	url Url, alias string, generator services.SlugGenerator,
+) (Url, error) {
+	url.Deleted = false
+	url.CreatedAt = time.Now()
+	url.VisitCount = 0
+	url.LastVisitedAt = time.Time{}
+	url.QRCodeImageUrl = ""
+
+	for attempt := 0; attempt < maxSlugAttempts; attempt++ {
+		if alias == "" {
+			slug, err := generator.Generate(url.OriginalUrl)
+			if err != nil {
+				log.Println(err)
+				return Url{}, fmt.Errorf("internal server error")
+			}
+
+			url.ShortUrlSlug = slug
+			url.CustomAlias = false
+		} else {
+			url.ShortUrlSlug = alias
+			url.CustomAlias = true
 		}
 
-		return fmt.Errorf("internal server error")
+		id, err := urlS.UrlRepository.Insert(context.TODO(), url)
+		if err == nil {
+			url.ID = id
+			return url, nil
+		}
+
+		if err != ErrDuplicateKey {
+			log.Println(err)
+			return Url{}, fmt.Errorf("internal server error")
+		}
+
+		if alias != "" {
+			return Url{}, &AliasConflictError{Alias: alias}
+		}
+
+		log.Println("Slug collision, retrying: ", url.ShortUrlSlug)
 	}
 
-	return fmt.Errorf("url with this alias already exist")
+	log.Println("Could not allocate a unique slug")
+	return Url{}, fmt.Errorf("internal server error")
 }
 
 func (urlS *UrlService) createQRCode(shortUrl string) (string, error) {
@@ -88,19 +229,21 @@ func (urlS *UrlService) createQRCode(shortUrl string) (string, error) {
 	return qrCodeBase64, nil
 }
 
-func (urlS *UrlService) generateAndUploadQRCode() (string, error) {
-	cfg, err := configs.LoadEnvs()
-	if err != nil {
-		return "", err
-	}
+// QRCodeStore keeps the QR code images of short urls.
+type QRCodeStore interface {
+	// Upload stores a base64 encoded PNG under name and returns its url and
+	// file id.
+	Upload(name string, base64Image string) (string, string, error)
+	Delete(fileId string) error
+}
 
-	shortUrl := fmt.Sprintf(
-		"%s/%s", cfg.URL_REDIRECT_PREFIX, urlS.Url.ShortUrlSlug,
-	)
+// imageKitQRCodes keeps QR codes on ImageKit.
+type imageKitQRCodes struct{}
 
-	base64Image, err := urlS.createQRCode(shortUrl)
+func newImageKit() (*imagekit.ImageKit, error) {
+	cfg, err := configs.LoadEnvs()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	ik := imagekit.NewFromParams(imagekit.NewParams{
@@ -109,51 +252,96 @@ func (urlS *UrlService) generateAndUploadQRCode() (string, error) {
 		UrlEndpoint: cfg.IMAGEKIT_URL_ENDPOINT,
 	})
 
+	return ik, nil
+}
+
+func (imageKitQRCodes) Upload(name string, base64Image string) (
+	string, string, error,
+) {
+	ik, err := newImageKit()
+	if err != nil {
+		return "", "", err
+	}
+
 	response, err := ik.Uploader.Upload(
 		context.TODO(), base64Image, uploader.UploadParam{
-			FileName: urlS.Url.ShortUrlSlug,
+			FileName: name,
 			Folder:   "url-shortener",
 		},
 	)
 	if err != nil {
-		return "", err
+		return "", "", err
+	}
+
+	return response.Data.Url, response.Data.FileId, nil
+}
+
+func (imageKitQRCodes) Delete(fileId string) error {
+	ik, err := newImageKit()
+	if err != nil {
+		return err
+	}
+
+	_, err = ik.Media.DeleteFile(context.TODO(), fileId)
+
+	return err
+}
+
+func (urlS *UrlService) qrCodes() QRCodeStore {
+	if urlS.QRCodes != nil {
+		return urlS.QRCodes
 	}
 
-	return response.Data.Url, nil
+	return imageKitQRCodes{}
 }
 
-func (urlS *UrlService) CreateShortUrl(alias string) (
-	map[string]string, error,
+// generateAndUploadQRCode returns the url and file id of the QR code for
+// slug.
+func (urlS *UrlService) generateAndUploadQRCode(slug string) (
+	string, string, error,
 ) {
-	err := urlS.getShortUrlSlug(alias)
+	cfg, err := configs.LoadEnvs()
 	if err != nil {
-		fmt.Println(err)
-		return nil, err
+		return "", "", err
 	}
 
-	qrCodeUrl, err := urlS.generateAndUploadQRCode()
+	shortUrl := fmt.Sprintf("%s/%s", cfg.URL_REDIRECT_PREFIX, slug)
+
+	base64Image, err := urlS.createQRCode(shortUrl)
 	if err != nil {
-		fmt.Println(err)
-		return nil, fmt.Errorf("internal server error")
+		return "", "", err
 	}
 
-	insertedRecord, err := urlS.UrlCollection.InsertOne(context.TODO(), bson.M{
-		"userId":         urlS.Url.UserId,
-		"deleted":        false,
-		"createdAt":      time.Now(),
-		"expiresAt":      urlS.Url.ExpiresAt,
-		"visitCount":     0,
-		"customAlias":    urlS.Url.CustomAlias,
-		"originalUrl":    urlS.Url.OriginalUrl,
-		"shortUrlSlug":   urlS.Url.ShortUrlSlug,
-		"lastVisitedAt":  time.Time{},
-		"qrCodeImageUrl": qrCodeUrl,
-	})
+	return urlS.qrCodes().Upload(slug, base64Image)
+}
+
+// deleteQRCode removes a QR code. Urls created before file ids were stored
+// have none, their QR code is left in place.
+func (urlS *UrlService) deleteQRCode(fileId string) {
+	if fileId == "" {
+		return
+	}
+
+	err := urlS.qrCodes().Delete(fileId)
 	if err != nil {
 		log.Println(err)
-		return nil, fmt.Errorf("internal server error")
 	}
+}
+
+type CreateUrlOptions struct {
+	Alias        string
+	SlugStrategy string
+	// Dedupe returns the user's existing live url for the same OriginalUrl
+	// instead of creating a new one. It is ignored when Alias, Password or
+	// any other setting of the url is set, and only plain urls are reused.
+	Dedupe bool
+	// Password makes visitors enter it before being redirected.
+	Password string
+}
 
+func (urlS *UrlService) shortUrlResponse(url Url, reused bool) (
+	map[string]any, error,
+) {
 	cfg, err := configs.LoadEnvs()
 	if err != nil {
 		log.Println(err)
@@ -161,108 +349,500 @@ func (urlS *UrlService) CreateShortUrl(alias string) (
 	}
 
 	shortUrl := fmt.Sprintf(
-		"%s/%s", cfg.URL_REDIRECT_PREFIX, urlS.Url.ShortUrlSlug,
+		"%s/%s", cfg.URL_REDIRECT_PREFIX, url.ShortUrlSlug,
 	)
 
-	id, ok := insertedRecord.InsertedID.(primitive.ObjectID)
-	if !ok {
-		log.Println("Unexpected type for insertedID")
-		return nil, fmt.Errorf("internal server error")
-	}
-
-	res := map[string]string{
-		"id":             id.Hex(),
+	res := map[string]any{
+		"id":             url.ID.Hex(),
 		"shortUrl":       shortUrl,
-		"originalUrl":    urlS.Url.OriginalUrl,
-		"qrCodeImageUrl": qrCodeUrl,
+		"originalUrl":    url.OriginalUrl,
+		"qrCodeImageUrl": url.QRCodeImageUrl,
+		"reused":         reused,
+		"protected":      url.PasswordHash != "",
+		"maxClicks":      url.MaxClicks,
 	}
 
 	return res, nil
 }
 
-func (urlS *UrlService) GetOriginalUrl() (Url, error) {
-	var urlRecord Url
+// hasLinkSettings reports whether url has any setting besides where it
+// leads, which rules it out for dedupe.
+func hasLinkSettings(url Url) bool {
+	return url.PasswordHash != "" || url.MaxClicks != 0 ||
+		!url.ExpiresAt.IsZero() || !url.ActivatesAt.IsZero() ||
+		url.ExpiredRedirectUrl != "" || url.InactiveRedirectUrl != "" ||
+		url.FallbackUrl != "" || len(url.TargetingRules) > 0 ||
+		len(url.SplitDestinations) > 0 || hasQuerySettings(url) ||
+		hasPreviewSettings(url)
+}
 
-	filter := bson.M{"shortUrlSlug": urlS.Url.ShortUrlSlug, "deleted": false}
-	err := urlS.UrlCollection.FindOne(context.TODO(), filter).Decode(&urlRecord)
-	if err != nil {
-		if err == mongo.ErrNoDocuments {
+// dedupeLocks serialises deduplicated creates of the same url by the same
+// user, so they agree on one url. Other instances of the server are not
+// covered, across them dedupe is best effort.
+var dedupeLocks = utils.NewKeyedMutex()
+
+func (urlS *UrlService) CreateShortUrl(url Url, opts CreateUrlOptions) (
+	map[string]any, error,
+) {
+	if opts.Dedupe && opts.Alias == "" && opts.Password == "" &&
+		!hasLinkSettings(url) {
+		unlock := dedupeLocks.Lock(url.UserId.Hex() + "|" + url.OriginalUrl)
+		defer unlock()
+
+		existing, err := urlS.UrlRepository.FindActiveByOriginalUrl(
+			context.TODO(), url.UserId, url.OriginalUrl, time.Now(),
+		)
+		if err != nil {
+			log.Println(err)
+			return nil, fmt.Errorf("internal server error")
+		}
+
+		for _, existingUrl := range existing {
+			if !hasLinkSettings(existingUrl) {
+				return urlS.shortUrlResponse(existingUrl, true)
+			}
+		}
+	}
+
+	url.PasswordHash = ""
+	if opts.Password != "" {
+		passwordHash, err := urlS.hashPassword(opts.Password)
+		if err != nil {
 			log.Println(err)
-			return Url{}, fmt.Errorf("invalid shorturl")
+			return nil, fmt.Errorf("internal server error")
 		}
 
+		url.PasswordHash = passwordHash
+	}
+
+	generator, err := urlS.slugGenerator(opts.SlugStrategy)
+	if err != nil {
+		log.Println(err)
+		return nil, fmt.Errorf("internal server error")
+	}
+
+	// The record is inserted before the QR code is uploaded so the slug is
+	// reserved and the QR code is never generated for a slug we lose.
+	url, err = urlS.insertWithUniqueSlug(url, opts.Alias, generator)
+	if err != nil {
+		fmt.Println(err)
+		return nil, err
+	}
+
+	qrCodeUrl, qrCodeFileId, err := urlS.generateAndUploadQRCode(url.ShortUrlSlug)
+	if err != nil {
+		fmt.Println(err)
+		urlS.releaseSlug(url.ID)
+		return nil, fmt.Errorf("internal server error")
+	}
+
+	err = urlS.UrlRepository.SetQRCode(
+		context.TODO(), url.ID, qrCodeUrl, qrCodeFileId,
+	)
+	if err != nil {
+		log.Println(err)
+		urlS.releaseSlug(url.ID)
+		urlS.deleteQRCode(qrCodeFileId)
+		return nil, fmt.Errorf("internal server error")
+	}
+
+	url.QRCodeImageUrl = qrCodeUrl
+	url.QRCodeFileId = qrCodeFileId
+
+	return urlS.shortUrlResponse(url, false)
+}
+
+// releaseSlug removes a url whose creation could not be completed, so its
+// slug becomes available again.
+func (urlS *UrlService) releaseSlug(id primitive.ObjectID) {
+	err := urlS.UrlRepository.Delete(context.TODO(), id)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+func (urlS *UrlService) GetOriginalUrl(slug string) (Url, error) {
+	urlRecord, err := urlS.UrlRepository.FindBySlug(context.TODO(), slug)
+	if err == ErrNotFound {
+		return Url{}, urlS.deletedOrUnknownError(slug)
+	} else if err != nil {
 		fmt.Println(err)
 		return Url{}, fmt.Errorf("internal server error")
 	}
 
-	if !urlRecord.ExpiresAt.IsZero() && urlRecord.ExpiresAt.Before(time.Now()) {
-		return Url{}, fmt.Errorf("url has expired")
+	now := time.Now()
+
+	if !urlRecord.ActivatesAt.IsZero() && urlRecord.ActivatesAt.After(now) {
+		return Url{}, urlS.unavailableError(
+			urlRecord, UrlInactiveReason, urlRecord.InactiveRedirectUrl,
+		)
+	}
+
+	if !urlRecord.ExpiresAt.IsZero() && urlRecord.ExpiresAt.Before(now) {
+		return Url{}, urlS.expiredError(urlRecord)
+	}
+
+	if urlRecord.MaxClicks > 0 && urlRecord.VisitCount >= urlRecord.MaxClicks {
+		return Url{}, urlS.expiredError(urlRecord)
 	}
 
 	return urlRecord, nil
 }
 
-func (urlS *UrlService) DeleteUrl() error {
-	filter := bson.M{
-		"_id":     urlS.Url.ID,
-		"userId":  urlS.Url.UserId,
-		"deleted": false,
+// deletedOrUnknownError tells slugs of trashed urls apart from slugs that
+// never existed or have been purged.
+func (urlS *UrlService) deletedOrUnknownError(slug string) error {
+	deleted, err := urlS.UrlRepository.FindDeletedBySlug(context.TODO(), slug)
+	if err == ErrNotFound {
+		return &UrlUnavailableError{Reason: UrlUnknownReason}
+	} else if err != nil {
+		log.Println(err)
+		return fmt.Errorf("internal server error")
+	}
+
+	return urlS.unavailableError(deleted, UrlDeletedReason, "")
+}
+
+func (urlS *UrlService) expiredError(url Url) error {
+	return urlS.unavailableError(url, UrlExpiredReason, url.ExpiredRedirectUrl)
+}
+
+// unavailableError picks the fallback for a url that cannot be visited:
+// the one for this reason, then the url's own, then its owner's.
+func (urlS *UrlService) unavailableError(
+	url Url, reason string, reasonFallbackUrl string,
+) error {
+	fallbackUrl := reasonFallbackUrl
+	if fallbackUrl == "" {
+		fallbackUrl = url.FallbackUrl
+	}
+
+	if fallbackUrl == "" && urlS.UserRepository != nil {
+		owner, err := urlS.UserRepository.FindByID(context.TODO(), url.UserId)
+		if err != nil && err != ErrNotFound {
+			log.Println(err)
+		}
+
+		fallbackUrl = owner.FallbackUrl
 	}
-	update := bson.M{"$set": bson.M{"deleted": true}}
-	result := urlS.UrlCollection.FindOneAndUpdate(context.TODO(), filter, update)
-	if result.Err() != nil {
-		if result.Err() == mongo.ErrNoDocuments {
+
+	return &UrlUnavailableError{Reason: reason, FallbackUrl: fallbackUrl}
+}
+
+// RegisterClick counts a visit to url before the visitor is redirected.
+// The click limit is enforced by the storage backend, so two concurrent
+// visitors can never both use the last click of a url.
+func (urlS *UrlService) RegisterClick(url Url, visitedAt time.Time) error {
+	err := urlS.UrlRepository.IncrementVisitCount(
+		context.TODO(), url.ID, visitedAt,
+	)
+	if err != nil {
+		if err == ErrNotFound {
+			return urlS.expiredError(url)
+		}
+
+		log.Println(err)
+		return fmt.Errorf("internal server error")
+	}
+
+	return nil
+}
+
+// RegisterBotClick counts a visit by a bot. Bots do not use up the
+// MaxClicks of a url.
+func (urlS *UrlService) RegisterBotClick(url Url) error {
+	err := urlS.UrlRepository.IncrementBotVisitCount(context.TODO(), url.ID)
+	if err != nil && err != ErrNotFound {
+		log.Println(err)
+		return fmt.Errorf("internal server error")
+	}
+
+	return nil
+}
+
+func (urlS *UrlService) DeleteUrl(
+	urlId primitive.ObjectID,
+	userId primitive.ObjectID,
+) error {
+	err := urlS.UrlRepository.SoftDelete(
+		context.TODO(), urlId, userId, time.Now(),
+	)
+	if err != nil {
+		if err == ErrNotFound {
 			return fmt.Errorf("no matching document found")
 		}
 
-		fmt.Println(result.Err())
+		fmt.Println(err)
 		return fmt.Errorf("internal server error")
 	}
 
 	return nil
 }
 
-func (urlS *UrlService) GetUrlsByUser(page int, limit int) (
-	[]Url, int64, error,
-) {
-	skip := (page - 1) * limit
+// UrlUpdate holds the fields of a url that can be edited. Nil fields are
+// left unchanged.
+type UrlUpdate struct {
+	OriginalUrl *string
+	ExpiresAt   *time.Time
+	ActivatesAt *time.Time
+	Alias       *string
+	// Password replaces the url's password, an empty one removes it.
+	Password *string
+	// MaxClicks replaces the url's click limit, 0 removes it.
+	MaxClicks *int64
+	// Empty fallback urls are removed.
+	ExpiredRedirectUrl  *string
+	InactiveRedirectUrl *string
+	FallbackUrl         *string
+	// TargetingRules replaces all rules, an empty list removes them.
+	TargetingRules *[]TargetingRule
+	// SplitDestinations replaces all destinations, an empty list stops the
+	// split.
+	SplitDestinations *[]SplitDestination
+	StickySplit       *bool
+	UtmSource         *string
+	UtmMedium         *string
+	UtmCampaign       *string
+	UtmTerm           *string
+	UtmContent        *string
+	QueryPassthrough  *string
+	Preview           *bool
+	OGTitle           *string
+	OGDescription     *string
+	OGImageUrl        *string
+}
+
+// ErrActivationAfterExpiry is returned when a url would be activated after
+// it expires.
+var ErrActivationAfterExpiry = errors.New(
+	"activation time must be before the expiry time",
+)
+
+// UpdateUrl edits the url identified by urlId, provided it belongs to
+// userId. The QR code is only regenerated when the slug changes.
+func (urlS *UrlService) UpdateUrl(
+	urlId primitive.ObjectID,
+	userId primitive.ObjectID,
+	update UrlUpdate,
+) (Url, error) {
+	existing, err := urlS.UrlRepository.FindByID(
+		context.TODO(), urlId, userId, false,
+	)
+	if err != nil {
+		if err == ErrNotFound {
+			return Url{}, fmt.Errorf("no matching document found")
+		}
+
+		log.Println(err)
+		return Url{}, fmt.Errorf("internal server error")
+	}
+
+	updated := existing
+	if update.OriginalUrl != nil {
+		updated.OriginalUrl = *update.OriginalUrl
+	}
 
-	sort := bson.M{"createdAt": -1}
-	opts := options.Find().SetSort(sort).SetSkip(
-		int64(skip),
-	).SetLimit(int64(limit))
+	if update.ExpiresAt != nil {
+		updated.ExpiresAt = *update.ExpiresAt
+	}
+
+	if update.Password != nil {
+		updated.PasswordHash = ""
+		if *update.Password != "" {
+			updated.PasswordHash, err = urlS.hashPassword(*update.Password)
+			if err != nil {
+				log.Println(err)
+				return Url{}, fmt.Errorf("internal server error")
+			}
+		}
+	}
 
-	filter := bson.M{"userId": urlS.Url.UserId, "deleted": false}
+	if update.ActivatesAt != nil {
+		updated.ActivatesAt = *update.ActivatesAt
+	}
+
+	if !updated.ActivatesAt.IsZero() && !updated.ExpiresAt.IsZero() &&
+		!updated.ActivatesAt.Before(updated.ExpiresAt) {
+		return Url{}, ErrActivationAfterExpiry
+	}
+
+	if update.MaxClicks != nil {
+		updated.MaxClicks = *update.MaxClicks
+	}
+
+	if update.ExpiredRedirectUrl != nil {
+		updated.ExpiredRedirectUrl = *update.ExpiredRedirectUrl
+	}
 
-	cursor, err := urlS.UrlCollection.Find(context.TODO(), filter, opts)
+	if update.InactiveRedirectUrl != nil {
+		updated.InactiveRedirectUrl = *update.InactiveRedirectUrl
+	}
+
+	if update.FallbackUrl != nil {
+		updated.FallbackUrl = *update.FallbackUrl
+	}
+
+	if update.TargetingRules != nil {
+		updated.TargetingRules = *update.TargetingRules
+	}
+
+	if update.SplitDestinations != nil {
+		updated.SplitDestinations = *update.SplitDestinations
+	}
+
+	if update.StickySplit != nil {
+		updated.StickySplit = *update.StickySplit
+	}
+
+	for _, field := range []struct {
+		value  *string
+		target *string
+	}{
+		{update.UtmSource, &updated.UtmSource},
+		{update.UtmMedium, &updated.UtmMedium},
+		{update.UtmCampaign, &updated.UtmCampaign},
+		{update.UtmTerm, &updated.UtmTerm},
+		{update.UtmContent, &updated.UtmContent},
+		{update.QueryPassthrough, &updated.QueryPassthrough},
+		{update.OGTitle, &updated.OGTitle},
+		{update.OGDescription, &updated.OGDescription},
+		{update.OGImageUrl, &updated.OGImageUrl},
+	} {
+		if field.value != nil {
+			*field.target = *field.value
+		}
+	}
+
+	if update.Preview != nil {
+		updated.Preview = *update.Preview
+	}
+
+	slugChanged := update.Alias != nil && *update.Alias != existing.ShortUrlSlug
+	if slugChanged {
+		updated.ShortUrlSlug = *update.Alias
+		updated.CustomAlias = true
+	}
+
+	// Saving before the QR code upload reserves the new slug, so we never
+	// upload a QR code for an alias someone else holds.
+	err = urlS.UrlRepository.Update(context.TODO(), updated)
 	if err != nil {
-		fmt.Println(err)
-		return []Url{}, 0, fmt.Errorf("internal server error")
+		if err == ErrDuplicateKey {
+			return Url{}, &AliasConflictError{Alias: updated.ShortUrlSlug}
+		} else if err == ErrNotFound {
+			return Url{}, fmt.Errorf("no matching document found")
+		}
+
+		log.Println(err)
+		return Url{}, fmt.Errorf("internal server error")
+	}
+
+	if !slugChanged {
+		urlS.recordRevision(existing, updated, userId)
+		return updated, nil
+	}
+
+	qrCodeUrl, qrCodeFileId, err := urlS.generateAndUploadQRCode(
+		updated.ShortUrlSlug,
+	)
+	if err != nil {
+		log.Println(err)
+		urlS.restoreUrl(existing)
+		return Url{}, fmt.Errorf("internal server error")
 	}
 
-	var urlRecords []Url
-	if err = cursor.All(context.TODO(), &urlRecords); err != nil {
+	updated.QRCodeImageUrl = qrCodeUrl
+	updated.QRCodeFileId = qrCodeFileId
+
+	err = urlS.UrlRepository.SetQRCode(
+		context.TODO(), updated.ID, qrCodeUrl, qrCodeFileId,
+	)
+	if err != nil {
 		log.Println(err)
+		urlS.restoreUrl(existing)
+		urlS.deleteQRCode(qrCodeFileId)
+		return Url{}, fmt.Errorf("internal server error")
+	}
+
+	urlS.deleteQRCode(existing.QRCodeFileId)
+	urlS.recordRevision(existing, updated, userId)
+
+	return updated, nil
+}
+
+// restoreUrl puts back a url whose update could not be completed.
+func (urlS *UrlService) restoreUrl(url Url) {
+	err := urlS.UrlRepository.Update(context.TODO(), url)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+func (urlS *UrlService) GetUrlsByUser(
+	userId primitive.ObjectID,
+	page int,
+	limit int,
+) ([]Url, int64, error) {
+	return urlS.urlsByUser(userId, false, page, limit)
+}
+
+func (urlS *UrlService) urlsByUser(
+	userId primitive.ObjectID,
+	deleted bool,
+	page int,
+	limit int,
+) ([]Url, int64, error) {
+	skip := (page - 1) * limit
+
+	urlRecords, err := urlS.UrlRepository.FindByUser(
+		context.TODO(), userId, deleted, int64(skip), int64(limit),
+	)
+	if err != nil {
+		fmt.Println(err)
 		return []Url{}, 0, fmt.Errorf("internal server error")
 	}
-	defer cursor.Close(context.TODO())
 
 	var urls []Url
 	for _, urlRecord := range urlRecords {
 		urls = append(urls, Url{
 			ID:             urlRecord.ID,
+			Deleted:        urlRecord.Deleted,
+			DeletedAt:      urlRecord.DeletedAt,
 			CreatedAt:      urlRecord.CreatedAt,
 			ExpiresAt:      urlRecord.ExpiresAt,
 			VisitCount:     urlRecord.VisitCount,
+			BotVisitCount:  urlRecord.BotVisitCount,
 			OriginalUrl:    urlRecord.OriginalUrl,
 			CustomAlias:    urlRecord.CustomAlias,
 			ShortUrlSlug:   urlRecord.ShortUrlSlug,
 			LastVisitedAt:  urlRecord.LastVisitedAt,
 			QRCodeImageUrl: urlRecord.QRCodeImageUrl,
+			PasswordHash:   urlRecord.PasswordHash,
+			MaxClicks:      urlRecord.MaxClicks,
+			ActivatesAt:    urlRecord.ActivatesAt,
+
+			ExpiredRedirectUrl:  urlRecord.ExpiredRedirectUrl,
+			InactiveRedirectUrl: urlRecord.InactiveRedirectUrl,
+			FallbackUrl:         urlRecord.FallbackUrl,
+			TargetingRules:      urlRecord.TargetingRules,
+			SplitDestinations:   urlRecord.SplitDestinations,
+			StickySplit:         urlRecord.StickySplit,
+			UtmSource:           urlRecord.UtmSource,
+			UtmMedium:           urlRecord.UtmMedium,
+			UtmCampaign:         urlRecord.UtmCampaign,
+			UtmTerm:             urlRecord.UtmTerm,
+			UtmContent:          urlRecord.UtmContent,
+			QueryPassthrough:    urlRecord.QueryPassthrough,
+			Preview:             urlRecord.Preview,
+			OGTitle:             urlRecord.OGTitle,
+			OGDescription:       urlRecord.OGDescription,
+			OGImageUrl:          urlRecord.OGImageUrl,
 		})
 	}
 
-	total, err := urlS.UrlCollection.CountDocuments(context.TODO(), filter)
+	total, err := urlS.UrlRepository.CountByUser(
+		context.TODO(), userId, deleted,
+	)
 	if err != nil {
 		log.Println(err)
 		return nil, 0, fmt.Errorf("internal server error")
@@ -271,30 +851,22 @@ func (urlS *UrlService) GetUrlsByUser(page int, limit int) (
 	return urls, total, nil
 }
 
-func (urlS *UrlService) SaveClickAnalytics() error {
-	_, err := urlS.VisitCollection.InsertOne(context.TODO(), bson.M{
-		"urlId":      urlS.Visit.UrlId,
-		"browser":    urlS.Visit.Browser,
-		"location":   urlS.Visit.Location,
-		"referrer":   urlS.Visit.Referrer,
-		"ipAddress":  urlS.Visit.IPAddress,
-		"visitedAt":  urlS.Visit.VisitedAt,
-		"deviceType": urlS.Visit.DeviceType,
-	})
-
+// SaveClickAnalytics stores visit. The visit itself is counted by
+// RegisterClick. It takes the visit as an argument as it is usually saved in
+// the background while urlS serves the next request.
+func (urlS *UrlService) SaveClickAnalytics(visit Visit) error {
+	cfg, err := configs.LoadEnvs()
 	if err != nil {
 		return err
 	}
 
-	filter := bson.M{"_id": urlS.Visit.UrlId}
-	update := bson.M{
-		"$inc": bson.M{"visitCount": 1},
-		"$set": bson.M{"lastVisitedAt": time.Now()},
-	}
-	res := urlS.UrlCollection.FindOneAndUpdate(context.TODO(), filter, update)
-	if res.Err() != nil {
-		return err
+	// The visitor hash is kept either way, so unique visitors can still be
+	// counted without raw ip addresses.
+	if cfg.STORE_VISITOR_IPS == "false" {
+		visit.IPAddress = ""
 	}
 
-	return nil
+	_, err = urlS.VisitRepository.Insert(context.TODO(), visit)
+
+	return err
 }
@@ -0,0 +1,311 @@
+package models_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Origho-precious/url-shortener/go/models"
+	"github.com/Origho-precious/url-shortener/go/repositories"
+	"github.com/Origho-precious/url-shortener/go/services"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	_ "modernc.org/sqlite"
+)
+
+// fakeQRCodes keeps uploaded QR codes in memory instead of on ImageKit.
+type fakeQRCodes struct {
+	mu    sync.Mutex
+	names map[string]string
+}
+
+func (f *fakeQRCodes) Upload(name string, _ string) (string, string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	fileId := fmt.Sprintf("file-%d", len(f.names))
+	f.names[fileId] = name
+
+	return "https://qr.test/" + name, fileId, nil
+}
+
+func (f *fakeQRCodes) Delete(fileId string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.names, fileId)
+
+	return nil
+}
+
+// testBackends returns a fresh set of repositories for every backend that
+// runs without a server.
+func testBackends(t *testing.T) map[string]*models.Repositories {
+	t.Helper()
+	t.Setenv("GIN_MODE", "test")
+
+	db, err := sql.Open("sqlite", ":memory:?_time_format=sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	// Every connection would get its own in-memory database.
+	db.SetMaxOpenConns(1)
+
+	sqlRepos, err := repositories.NewSQLRepositories(db, "sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return map[string]*models.Repositories{
+		"memory": repositories.NewMemoryRepositories(),
+		"sqlite": sqlRepos,
+	}
+}
+
+func newTestUrlService(repos *models.Repositories) *models.UrlService {
+	return &models.UrlService{
+		UrlRepository:      repos.Urls,
+		VisitRepository:    repos.Visits,
+		CounterRepository:  repos.Counters,
+		RevisionRepository: repos.UrlRevisions,
+		UserRepository:     repos.Users,
+		RollupRepository:   repos.VisitRollups,
+		QRCodes:            &fakeQRCodes{names: map[string]string{}},
+	}
+}
+
+func TestCreateShortUrlConcurrently(t *testing.T) {
+	const links = 2000
+	const workers = 50
+
+	for name, repos := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			urlS := newTestUrlService(repos)
+			userId := primitive.NewObjectID()
+
+			type created struct {
+				originalUrl string
+				response    map[string]any
+			}
+
+			results := make(chan created, links)
+			errs := make(chan error, links)
+			jobs := make(chan int)
+
+			var wg sync.WaitGroup
+			for w := 0; w < workers; w++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+
+					for i := range jobs {
+						originalUrl := fmt.Sprintf("https://example.com/%d", i)
+						res, err := urlS.CreateShortUrl(models.Url{
+							UserId:      userId,
+							OriginalUrl: originalUrl,
+						}, models.CreateUrlOptions{
+							SlugStrategy: services.SlugStrategies[i%len(services.SlugStrategies)],
+						})
+						if err != nil {
+							errs <- err
+							continue
+						}
+
+						results <- created{originalUrl: originalUrl, response: res}
+					}
+				}()
+			}
+
+			for i := 0; i < links; i++ {
+				jobs <- i
+			}
+			close(jobs)
+			wg.Wait()
+			close(results)
+			close(errs)
+
+			for err := range errs {
+				t.Fatalf("CreateShortUrl failed: %v", err)
+			}
+
+			slugs := make(map[string]bool, links)
+			for result := range results {
+				res := result.response
+				slug := strings.TrimPrefix(res["shortUrl"].(string), "/")
+
+				if slugs[slug] {
+					t.Fatalf("slug %q was handed out twice", slug)
+				}
+				slugs[slug] = true
+
+				if res["originalUrl"] != result.originalUrl {
+					t.Fatalf(
+						"created %s but got back %s",
+						result.originalUrl, res["originalUrl"],
+					)
+				}
+
+				if res["qrCodeImageUrl"] != "https://qr.test/"+slug {
+					t.Fatalf(
+						"QR code of %q is %s", slug, res["qrCodeImageUrl"],
+					)
+				}
+
+				stored, err := urlS.GetOriginalUrl(slug)
+				if err != nil {
+					t.Fatalf("could not find %q: %v", slug, err)
+				}
+
+				if stored.OriginalUrl != result.originalUrl {
+					t.Fatalf(
+						"%q leads to %s instead of %s",
+						slug, stored.OriginalUrl, result.originalUrl,
+					)
+				}
+			}
+
+			if len(slugs) != links {
+				t.Fatalf("created %d links, want %d", len(slugs), links)
+			}
+		})
+	}
+}
+
+func TestCreateShortUrlAliasRace(t *testing.T) {
+	const attempts = 200
+
+	for name, repos := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			urlS := newTestUrlService(repos)
+
+			var wg sync.WaitGroup
+			var mu sync.Mutex
+			won, conflicts := 0, 0
+
+			for i := 0; i < attempts; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+
+					_, err := urlS.CreateShortUrl(models.Url{
+						UserId:      primitive.NewObjectID(),
+						OriginalUrl: fmt.Sprintf("https://example.com/%d", i),
+					}, models.CreateUrlOptions{Alias: "launch"})
+
+					mu.Lock()
+					defer mu.Unlock()
+
+					var conflictErr *models.AliasConflictError
+					switch {
+					case err == nil:
+						won++
+					case errors.As(err, &conflictErr):
+						conflicts++
+					default:
+						t.Errorf("CreateShortUrl failed: %v", err)
+					}
+				}(i)
+			}
+			wg.Wait()
+
+			if won != 1 || conflicts != attempts-1 {
+				t.Fatalf(
+					"%d requests got the alias and %d conflicted, want 1 and %d",
+					won, conflicts, attempts-1,
+				)
+			}
+		})
+	}
+}
+
+// slowLookups widens the gap between looking up an existing url and
+// inserting a new one, where concurrent creates could race.
+type slowLookups struct {
+	models.UrlRepository
+}
+
+func (r slowLookups) FindActiveByOriginalUrl(
+	ctx context.Context, userId primitive.ObjectID, originalUrl string,
+	now time.Time,
+) ([]models.Url, error) {
+	urls, err := r.UrlRepository.FindActiveByOriginalUrl(
+		ctx, userId, originalUrl, now,
+	)
+	time.Sleep(time.Millisecond)
+
+	return urls, err
+}
+
+func TestCreateShortUrlDedupe(t *testing.T) {
+	const attempts = 100
+
+	for name, repos := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			urlS := newTestUrlService(repos)
+			urlS.UrlRepository = slowLookups{repos.Urls}
+			userId := primitive.NewObjectID()
+			plain := models.Url{
+				UserId:      userId,
+				OriginalUrl: "https://example.com/retried",
+			}
+
+			var wg sync.WaitGroup
+			var mu sync.Mutex
+			ids := map[any]bool{}
+
+			for i := 0; i < attempts; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+
+					res, err := urlS.CreateShortUrl(
+						plain, models.CreateUrlOptions{Dedupe: true},
+					)
+					if err != nil {
+						t.Errorf("CreateShortUrl failed: %v", err)
+						return
+					}
+
+					mu.Lock()
+					defer mu.Unlock()
+					ids[res["id"]] = true
+				}()
+			}
+			wg.Wait()
+
+			if len(ids) != 1 {
+				t.Fatalf("concurrent retries created %d urls, want 1", len(ids))
+			}
+
+			expiring := plain
+			expiring.ExpiresAt = time.Now().Add(time.Hour)
+
+			res, err := urlS.CreateShortUrl(
+				expiring, models.CreateUrlOptions{Dedupe: true},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if res["reused"] == true || ids[res["id"]] {
+				t.Fatalf("a url with an expiry reused %v", res["id"])
+			}
+
+			res, err = urlS.CreateShortUrl(
+				plain, models.CreateUrlOptions{Dedupe: true},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if res["reused"] != true || !ids[res["id"]] {
+				t.Fatalf("a plain url did not reuse the plain one: %v", res)
+			}
+		})
+	}
+}
@@ -12,10 +12,7 @@ import (
 	"github.com/Origho-precious/url-shortener/go/configs"
 	"github.com/Origho-precious/url-shortener/go/services"
 	"github.com/golang-jwt/jwt/v5"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -27,13 +24,29 @@ type User struct {
 	AuthToken     string
 	CreatedAt     time.Time
 	EmailVerified bool
+	// FallbackUrl is where visitors of the user's unavailable urls are sent
+	// when the url has no fallback of its own.
+	FallbackUrl string
+}
+
+type ForgotPassword struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	UserEmail string
+	CreatedAt time.Time
+}
+
+type VerificationToken struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	Token     string
+	UserId    primitive.ObjectID `bson:"userId,omitempty"`
+	CreatedAt time.Time
 }
 
 type UserService struct {
 	User                        User
-	UserCollection              *mongo.Collection
-	ForgotPasswordCollection    *mongo.Collection
-	VerificationTokenCollection *mongo.Collection
+	UserRepository              UserRepository
+	ForgotPasswordRepository    ForgotPasswordRepository
+	VerificationTokenRepository VerificationTokenRepository
 }
 
 func (us *UserService) hashPassword() ([]byte, error) {
@@ -98,11 +111,11 @@ func (us *UserService) sendEmailVerificationEmail() error {
 	}
 
 	// Save token to verificationToken collection
-	_, err = us.VerificationTokenCollection.InsertOne(
-		context.Background(), bson.M{
-			"token":     randNum,
-			"userId":    us.User.ID,
-			"createdAt": time.Now(),
+	_, err = us.VerificationTokenRepository.Insert(
+		context.Background(), VerificationToken{
+			Token:     randNum,
+			UserId:    us.User.ID,
+			CreatedAt: time.Now(),
 		},
 	)
 	if err != nil {
@@ -136,12 +149,9 @@ func (us *UserService) CreateUser() (User, error) {
 	defer cancel()
 
 	// Check if user with email already exists
-	var existingUser User
-
-	filter := bson.M{"email": us.User.Email}
-	findErr := us.UserCollection.FindOne(ctx, filter).Decode(&existingUser)
+	existingUser, findErr := us.UserRepository.FindByEmail(ctx, us.User.Email)
 
-	if findErr == mongo.ErrNoDocuments {
+	if findErr == ErrNotFound {
 		hashByte, err := us.hashPassword()
 		if err != nil {
 			return User{}, err
@@ -150,23 +160,17 @@ func (us *UserService) CreateUser() (User, error) {
 		passwordHash := string(hashByte)
 
 		// Create User record in DB
-		userRecord, err := us.UserCollection.InsertOne(ctx, bson.M{
-			"email":         us.User.Email,
-			"fullName":      us.User.FullName,
-			"password":      passwordHash,
-			"createdAt":     time.Now(),
-			"emailVerified": false,
+		insertedID, err := us.UserRepository.Insert(ctx, User{
+			Email:         us.User.Email,
+			FullName:      us.User.FullName,
+			Password:      passwordHash,
+			CreatedAt:     time.Now(),
+			EmailVerified: false,
 		})
 		if err != nil {
 			return User{}, err
 		}
 
-		insertedID, ok := userRecord.InsertedID.(primitive.ObjectID)
-		if !ok {
-			log.Println("Unexpected type for insertedID")
-			return User{}, fmt.Errorf("internal server error")
-		}
-
 		us.User = User{
 			ID:            insertedID,
 			Email:         us.User.Email,
@@ -202,12 +206,8 @@ func (us *UserService) AuthenticateUser() (User, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	var userData User
-
-	filter := bson.M{"email": us.User.Email}
-
-	err := us.UserCollection.FindOne(ctx, filter).Decode(&userData)
-	if err == mongo.ErrNoDocuments {
+	userData, err := us.UserRepository.FindByEmail(ctx, us.User.Email)
+	if err == ErrNotFound {
 		return User{}, fmt.Errorf("invalid email or password")
 	} else if err != nil {
 		return User{}, err
@@ -239,17 +239,10 @@ func (us *UserService) VerifyUserEmail(token string) error {
 	defer cancel()
 
 	// Find the token record
-	var tokenRecord struct {
-		Token  string
-		ID     primitive.ObjectID `bson:"_id,omitempty"`
-		UserID primitive.ObjectID `bson:"userId,omitempty"`
-	}
-
-	tokenFilter := bson.M{"userId": us.User.ID, "token": token}
-	err := us.VerificationTokenCollection.FindOne(ctx, tokenFilter).Decode(
-		&tokenRecord,
+	tokenRecord, err := us.VerificationTokenRepository.Find(
+		ctx, us.User.ID, token,
 	)
-	if err == mongo.ErrNoDocuments {
+	if err == ErrNotFound {
 		log.Println(err)
 		return fmt.Errorf("invalid token")
 	} else if err != nil {
@@ -258,25 +251,16 @@ func (us *UserService) VerifyUserEmail(token string) error {
 	}
 
 	// Update the user's emailVerified field to true
-	var updatedUser User
+	_, err = us.UserRepository.SetEmailVerified(ctx, us.User.ID)
 
-	userFilter := bson.M{"_id": us.User.ID}
-	update := bson.M{"$set": bson.M{"emailVerified": true}}
-	options := options.FindOneAndUpdate().SetReturnDocument(options.After)
-
-	err = us.UserCollection.FindOneAndUpdate(ctx, userFilter, update, options).Decode(&updatedUser)
-
-	if err == mongo.ErrNoDocuments {
+	if err == ErrNotFound {
 		return fmt.Errorf("internal server error")
 	} else if err != nil {
 		return err
 	}
 
 	// Delete the VerficationToken record
-	tokenFilter = bson.M{"userId": us.User.ID, "token": token}
-	err = us.VerificationTokenCollection.FindOneAndDelete(
-		ctx, tokenFilter,
-	).Decode(&tokenRecord)
+	err = us.VerificationTokenRepository.Delete(ctx, tokenRecord.ID)
 
 	if err != nil {
 		log.Println(err)
@@ -290,18 +274,10 @@ func (us *UserService) ResendEmailVerificationToken() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	var verifTokenRecord struct {
-		ID        primitive.ObjectID `bson:"_id,omitempty"`
-		Token     string
-		UserId    string
-		createdAt time.Time
-	}
-
-	filter := bson.M{"userId": us.User.ID}
-	err := us.VerificationTokenCollection.FindOne(ctx, filter).Decode(
-		&verifTokenRecord,
+	verifTokenRecord, err := us.VerificationTokenRepository.FindByUser(
+		ctx, us.User.ID,
 	)
-	if err == mongo.ErrNoDocuments {
+	if err == ErrNotFound {
 		// Create new token and send
 		err = us.sendEmailVerificationEmail()
 		if err != nil {
@@ -345,11 +321,8 @@ func (us *UserService) ForgotPassword() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	var existingUser User
-
-	filter := bson.M{"email": us.User.Email}
-	err := us.UserCollection.FindOne(ctx, filter).Decode(&existingUser)
-	if err == mongo.ErrNoDocuments {
+	existingUser, err := us.UserRepository.FindByEmail(ctx, us.User.Email)
+	if err == ErrNotFound {
 		return fmt.Errorf(
 			"no account associated with email address: %s", us.User.Email,
 		)
@@ -357,19 +330,14 @@ func (us *UserService) ForgotPassword() error {
 		return err
 	}
 
-	res, err := us.ForgotPasswordCollection.InsertOne(ctx, bson.M{
-		"userEmail": us.User.Email,
-		"createdAt": time.Now(),
+	insertedID, err := us.ForgotPasswordRepository.Insert(ctx, ForgotPassword{
+		UserEmail: us.User.Email,
+		CreatedAt: time.Now(),
 	})
 	if err != nil {
 		return err
 	}
 
-	insertedID, ok := res.InsertedID.(primitive.ObjectID)
-	if !ok {
-		return fmt.Errorf("unexpected type for InsertedID")
-	}
-
 	cfg, err := configs.LoadEnvs()
 	if err != nil {
 		return err
@@ -403,24 +371,16 @@ func (us *UserService) ResetPassword(token string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	var resetPasswordRecord struct {
-		ID        primitive.ObjectID `bson:"_id,omitempty"`
-		Token     string
-		UserEmail string
-		createdAt time.Time
-	}
-
 	objectID, err := primitive.ObjectIDFromHex(token)
 	if err != nil {
 		log.Println(err)
 		return fmt.Errorf("internal server error")
 	}
 
-	filter := bson.M{"_id": objectID}
-	err = us.ForgotPasswordCollection.FindOne(ctx, filter).Decode(
-		&resetPasswordRecord,
+	resetPasswordRecord, err := us.ForgotPasswordRepository.FindByID(
+		ctx, objectID,
 	)
-	if err == mongo.ErrNoDocuments {
+	if err == ErrNotFound {
 		log.Println(err)
 		return fmt.Errorf("invalid token")
 	} else if err != nil {
@@ -434,23 +394,18 @@ func (us *UserService) ResetPassword(token string) error {
 		return fmt.Errorf("internal server error")
 	}
 
-	filter = bson.M{"email": resetPasswordRecord.UserEmail}
-	update := bson.M{"$set": bson.M{
-		"password": string(hashedPassword), "emailVerified": true,
-	}}
-	res := us.UserCollection.FindOneAndUpdate(ctx, filter, update)
-	if res.Err() != nil {
-		log.Println(res.Err())
+	err = us.UserRepository.UpdatePassword(
+		ctx, resetPasswordRecord.UserEmail, string(hashedPassword),
+	)
+	if err != nil {
+		log.Println(err)
 		return fmt.Errorf("internal server error")
 	}
 
 	go func() {
-		filter = bson.M{"_id": objectID}
-		res := us.ForgotPasswordCollection.FindOneAndDelete(
-			context.Background(), filter,
-		)
-		if res.Err() != nil {
-			log.Println(res.Err())
+		err := us.ForgotPasswordRepository.Delete(context.Background(), objectID)
+		if err != nil {
+			log.Println(err)
 			return
 		}
 
@@ -464,10 +419,7 @@ func (us *UserService) GetUser() (User, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	var user User
-
-	filter := bson.M{"_id": us.User.ID}
-	err := us.UserCollection.FindOne(ctx, filter).Decode(&user)
+	user, err := us.UserRepository.FindByID(ctx, us.User.ID)
 	if err != nil {
 		log.Println(err)
 		return User{}, err
@@ -479,19 +431,34 @@ func (us *UserService) GetUser() (User, error) {
 		FullName:      user.FullName,
 		CreatedAt:     user.CreatedAt,
 		EmailVerified: user.EmailVerified,
+		FallbackUrl:   user.FallbackUrl,
 	}, nil
 }
 
 func (us *UserService) UpdateUserFullName() (User, error) {
-	var user User
+	user, err := us.UserRepository.UpdateFullName(
+		context.TODO(), us.User.ID, us.User.FullName,
+	)
 
-	filter := bson.M{"_id": us.User.ID}
-	update := bson.M{"$set": bson.M{"fullName": us.User.FullName}}
-	options := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	if err != nil {
+		log.Println(err)
+		return User{}, err
+	}
 
-	err := us.UserCollection.FindOneAndUpdate(
-		context.TODO(), filter, update, options,
-	).Decode(&user)
+	return User{
+		ID:            user.ID,
+		Email:         user.Email,
+		FullName:      user.FullName,
+		CreatedAt:     user.CreatedAt,
+		EmailVerified: user.EmailVerified,
+		FallbackUrl:   user.FallbackUrl,
+	}, nil
+}
+
+func (us *UserService) UpdateUserFallbackUrl() (User, error) {
+	user, err := us.UserRepository.UpdateFallbackUrl(
+		context.TODO(), us.User.ID, us.User.FallbackUrl,
+	)
 
 	if err != nil {
 		log.Println(err)
@@ -504,5 +471,6 @@ func (us *UserService) UpdateUserFullName() (User, error) {
 		FullName:      user.FullName,
 		CreatedAt:     user.CreatedAt,
 		EmailVerified: user.EmailVerified,
+		FallbackUrl:   user.FallbackUrl,
 	}, nil
 }
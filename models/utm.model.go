@@ -0,0 +1,167 @@
+package models
+
+import (
+	"fmt"
+	neturl "net/url"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxUtmParamLength bounds the length of each stored UTM parameter.
+const MaxUtmParamLength = 256
+
+// How query parameters a visitor adds to a short url are passed on to the
+// destination. QueryPassthroughOff drops them.
+const (
+	QueryPassthroughOff = ""
+	// QueryPassthroughKeep forwards parameters the destination does not
+	// have yet, its own values win.
+	QueryPassthroughKeep = "keep"
+	// QueryPassthroughOverride forwards every parameter, replacing the
+	// destination's values for the same key.
+	QueryPassthroughOverride = "override"
+	// QueryPassthroughAppend forwards every parameter, keeping both values
+	// for keys the destination already has.
+	QueryPassthroughAppend = "append"
+)
+
+// QueryPassthroughModes lists the modes accepted for Url.QueryPassthrough.
+var QueryPassthroughModes = []string{
+	QueryPassthroughKeep,
+	QueryPassthroughOverride,
+	QueryPassthroughAppend,
+}
+
+// utmParams returns the UTM parameters stored on url by query key.
+func utmParams(url Url) [][2]string {
+	return [][2]string{
+		{"utm_source", url.UtmSource},
+		{"utm_medium", url.UtmMedium},
+		{"utm_campaign", url.UtmCampaign},
+		{"utm_term", url.UtmTerm},
+		{"utm_content", url.UtmContent},
+	}
+}
+
+func hasUtmParams(url Url) bool {
+	for _, param := range utmParams(url) {
+		if param[1] != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hasQuerySettings reports whether url changes the query of its
+// destinations.
+func hasQuerySettings(url Url) bool {
+	return hasUtmParams(url) || url.QueryPassthrough != QueryPassthroughOff
+}
+
+// ValidateQuerySettings checks the UTM parameters and query passthrough mode
+// of url before it is saved.
+func ValidateQuerySettings(url Url) error {
+	for _, param := range utmParams(url) {
+		if utf8.RuneCountInString(param[1]) > MaxUtmParamLength {
+			return fmt.Errorf(
+				"%s must be at most %d characters long",
+				param[0], MaxUtmParamLength,
+			)
+		}
+	}
+
+	if url.QueryPassthrough != QueryPassthroughOff &&
+		!slices.Contains(QueryPassthroughModes, url.QueryPassthrough) {
+		return fmt.Errorf(
+			"queryPassthrough must be one of: %s",
+			strings.Join(QueryPassthroughModes, ", "),
+		)
+	}
+
+	return nil
+}
+
+// ApplyQueryParams adds the stored UTM parameters of url to destination,
+// replacing any it already has, then passes on incoming, the query of the
+// visitor's request, according to url.QueryPassthrough. The query of
+// destination is left as it was written, apart from the parameters that are
+// replaced, and the added ones are appended after it.
+func ApplyQueryParams(
+	url Url, destination string, incoming neturl.Values,
+) (string, error) {
+	if url.QueryPassthrough == QueryPassthroughOff {
+		incoming = nil
+	}
+
+	if !hasUtmParams(url) && len(incoming) == 0 {
+		return destination, nil
+	}
+
+	parsed, err := neturl.Parse(destination)
+	if err != nil {
+		return "", err
+	}
+
+	existing := parsed.Query()
+	added := neturl.Values{}
+	replaced := make(map[string]bool)
+
+	for _, param := range utmParams(url) {
+		if param[1] != "" {
+			added.Set(param[0], param[1])
+			replaced[param[0]] = true
+		}
+	}
+
+	for key, values := range incoming {
+		_, inDestination := existing[key]
+		_, inAdded := added[key]
+		exists := inDestination || inAdded
+
+		switch {
+		case url.QueryPassthrough == QueryPassthroughOverride:
+			added[key] = values
+			replaced[key] = true
+		case !exists:
+			added[key] = values
+		case url.QueryPassthrough == QueryPassthroughAppend:
+			added[key] = append(added[key], values...)
+		}
+	}
+
+	rawQuery := withoutQueryKeys(parsed.RawQuery, replaced)
+	if len(added) > 0 {
+		if rawQuery != "" {
+			rawQuery += "&"
+		}
+
+		rawQuery += added.Encode()
+	}
+
+	parsed.RawQuery = rawQuery
+
+	return parsed.String(), nil
+}
+
+// withoutQueryKeys drops the parameters named in keys from rawQuery and
+// leaves the others exactly as they were written.
+func withoutQueryKeys(rawQuery string, keys map[string]bool) string {
+	if rawQuery == "" || len(keys) == 0 {
+		return rawQuery
+	}
+
+	var kept []string
+	for _, pair := range strings.Split(rawQuery, "&") {
+		key, _, _ := strings.Cut(pair, "=")
+		if unescaped, err := neturl.QueryUnescape(key); err == nil &&
+			keys[unescaped] {
+			continue
+		}
+
+		kept = append(kept, pair)
+	}
+
+	return strings.Join(kept, "&")
+}
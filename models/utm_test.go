@@ -0,0 +1,76 @@
+package models_test
+
+import (
+	neturl "net/url"
+	"testing"
+
+	"github.com/Origho-precious/url-shortener/go/models"
+)
+
+// TestApplyQueryParamsKeepsDestinationQuery checks that the query of the
+// destination reaches the visitor byte for byte. Signed urls depend on the
+// order and escaping of their parameters.
+func TestApplyQueryParamsKeepsDestinationQuery(t *testing.T) {
+	const destination = "https://example.com/download?b=2&a=x%20y&sig=abc+def%2F"
+
+	tests := []struct {
+		name        string
+		url         models.Url
+		destination string
+		incoming    neturl.Values
+		want        string
+	}{
+		{
+			name: "utm parameters",
+			url:  models.Url{UtmSource: "news letter", UtmMedium: "email"},
+			want: destination + "&utm_medium=email&utm_source=news+letter",
+		},
+		{
+			name:     "passthrough of new parameters",
+			url:      models.Url{QueryPassthrough: models.QueryPassthroughKeep},
+			incoming: neturl.Values{"ref": {"a&b"}, "b": {"3"}},
+			want:     destination + "&ref=a%26b",
+		},
+		{
+			name:     "passthrough appending to a parameter",
+			url:      models.Url{QueryPassthrough: models.QueryPassthroughAppend},
+			incoming: neturl.Values{"b": {"3"}},
+			want:     destination + "&b=3",
+		},
+		{
+			name:     "passthrough overriding a parameter",
+			url:      models.Url{QueryPassthrough: models.QueryPassthroughOverride},
+			incoming: neturl.Values{"b": {"3"}},
+			want:     "https://example.com/download?a=x%20y&sig=abc+def%2F&b=3",
+		},
+		{
+			name:        "utm parameter the destination already has",
+			url:         models.Url{UtmSource: "ads"},
+			destination: "https://example.com/?utm_source=old&id=%7E1",
+			want:        "https://example.com/?id=%7E1&utm_source=ads",
+		},
+		{
+			name: "nothing to add",
+			url:  models.Url{QueryPassthrough: models.QueryPassthroughKeep},
+			want: destination,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			target := test.destination
+			if target == "" {
+				target = destination
+			}
+
+			got, err := models.ApplyQueryParams(test.url, target, test.incoming)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != test.want {
+				t.Errorf("ApplyQueryParams() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"sync"
+	"time"
+)
+
+// VisitorSalt is the random salt visitor hashes of one UTC day are made
+// with. Salts are deleted once their day is over, so a hash cannot be traced
+// back to an ip address by hashing every address again.
+type VisitorSalt struct {
+	// Day is formatted as YYYY-MM-DD.
+	Day  string `bson:"_id"`
+	Salt string
+}
+
+const visitorSaltDayLayout = "2006-01-02"
+
+// VisitorHasher identifies visitors without keeping who they are. The same
+// ip address and user agent hash to the same value for a day, after which
+// the salt rotates.
+type VisitorHasher struct {
+	repository VisitorSaltRepository
+
+	mu   sync.Mutex
+	salt VisitorSalt
+}
+
+func NewVisitorHasher(repository VisitorSaltRepository) *VisitorHasher {
+	return &VisitorHasher{repository: repository}
+}
+
+// Hash returns the visitor hash of ipAddress and userAgent. It always uses
+// the salt of the current UTC day, since the salts of past days may already
+// be deleted and making them again would split their visitors in two.
+func (h *VisitorHasher) Hash(ipAddress string, userAgent string) (string, error) {
+	salt, err := h.saltOf(time.Now().UTC().Format(visitorSaltDayLayout))
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	hash.Write([]byte(salt))
+	hash.Write([]byte(ipAddress))
+	// Keeps "1.2.3.4" + "5x" apart from "1.2.3.45" + "x".
+	hash.Write([]byte{0})
+	hash.Write([]byte(userAgent))
+
+	return hex.EncodeToString(hash.Sum(nil)[:16]), nil
+}
+
+// saltOf returns the salt of day, agreeing on it with other instances
+// through the repository when the day changes. Salts only move forward, so
+// a clock running behind never brings back the salt of a day that is over.
+func (h *VisitorHasher) saltOf(day string) (string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.salt.Day >= day {
+		return h.salt.Salt, nil
+	}
+
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+
+	salt, err := h.repository.FindOrInsert(context.TODO(), VisitorSalt{
+		Day:  day,
+		Salt: hex.EncodeToString(random),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	h.salt = salt
+
+	err = h.repository.DeleteBefore(context.TODO(), day)
+	if err != nil {
+		log.Println(err)
+	}
+
+	return salt.Salt, nil
+}
@@ -0,0 +1,22 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+)
+
+type memoryCounterRepository struct {
+	mu       sync.Mutex
+	counters map[string]uint64
+}
+
+func (r *memoryCounterRepository) Next(_ context.Context, name string) (
+	uint64, error,
+) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.counters[name]++
+
+	return r.counters[name], nil
+}
@@ -0,0 +1,36 @@
+package repositories
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoCounterRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoCounterRepository) Next(ctx context.Context, name string) (
+	uint64, error,
+) {
+	var counter struct {
+		Value int64
+	}
+
+	filter := bson.M{"_id": name}
+	update := bson.M{"$inc": bson.M{"value": int64(1)}}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(
+		options.After,
+	)
+
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(
+		&counter,
+	)
+	if err != nil {
+		return 0, mongoError(err)
+	}
+
+	return uint64(counter.Value), nil
+}
@@ -0,0 +1,24 @@
+package repositories
+
+import "context"
+
+type sqlCounterRepository struct {
+	store *sqlStore
+}
+
+func (r *sqlCounterRepository) Next(ctx context.Context, name string) (
+	uint64, error,
+) {
+	var value int64
+
+	err := r.store.queryRow(ctx, `INSERT INTO counters (name, value)
+		VALUES (?, 1)
+		ON CONFLICT (name) DO UPDATE SET value = counters.value + 1
+		RETURNING value`, name,
+	).Scan(&value)
+	if err != nil {
+		return 0, sqlError(err)
+	}
+
+	return uint64(value), nil
+}
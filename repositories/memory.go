@@ -0,0 +1,41 @@
+package repositories
+
+import (
+	"github.com/Origho-precious/url-shortener/go/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// NewMemoryRepositories returns repositories that keep every record in
+// process memory. Data is lost on restart, so it is only meant for local
+// development and tests.
+func NewMemoryRepositories() *models.Repositories {
+	return &models.Repositories{
+		Urls: &memoryUrlRepository{
+			urls: map[primitive.ObjectID]models.Url{},
+		},
+		Visits: &memoryVisitRepository{
+			visits: map[primitive.ObjectID]models.Visit{},
+		},
+		VisitRollups: &memoryVisitRollupRepository{
+			rollups: map[memoryRollupKey]models.VisitRollup{},
+		},
+		VisitorSalts: &memoryVisitorSaltRepository{
+			salts: map[string]models.VisitorSalt{},
+		},
+		UrlRevisions: &memoryUrlRevisionRepository{
+			revisions: map[primitive.ObjectID]models.UrlRevision{},
+		},
+		Counters: &memoryCounterRepository{
+			counters: map[string]uint64{},
+		},
+		Users: &memoryUserRepository{
+			users: map[primitive.ObjectID]models.User{},
+		},
+		ForgotPasswords: &memoryForgotPasswordRepository{
+			records: map[primitive.ObjectID]models.ForgotPassword{},
+		},
+		VerificationTokens: &memoryVerificationTokenRepository{
+			records: map[primitive.ObjectID]models.VerificationToken{},
+		},
+	}
+}
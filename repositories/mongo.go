@@ -0,0 +1,203 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Origho-precious/url-shortener/go/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// NewMongoRepositories creates any missing indexes and returns repositories
+// backed by DB.
+func NewMongoRepositories(DB *mongo.Database) (*models.Repositories, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	err := ensureMongoIndexes(ctx, DB)
+	if err != nil {
+		return nil, fmt.Errorf("error creating indexes: %w", err)
+	}
+
+	return &models.Repositories{
+		Urls:   &mongoUrlRepository{collection: DB.Collection("Urls")},
+		Visits: &mongoVisitRepository{collection: DB.Collection("Visits")},
+		VisitRollups: &mongoVisitRollupRepository{
+			collection: DB.Collection("VisitRollups"),
+			progress:   DB.Collection("RollupProgress"),
+		},
+		VisitorSalts: &mongoVisitorSaltRepository{
+			collection: DB.Collection("VisitorSalts"),
+		},
+		UrlRevisions: &mongoUrlRevisionRepository{
+			collection: DB.Collection("UrlRevisions"),
+		},
+		Counters: &mongoCounterRepository{
+			collection: DB.Collection("Counters"),
+		},
+		Users: &mongoUserRepository{collection: DB.Collection("Users")},
+		ForgotPasswords: &mongoForgotPasswordRepository{
+			collection: DB.Collection("ForgotPassword"),
+		},
+		VerificationTokens: &mongoVerificationTokenRepository{
+			collection: DB.Collection("VerificationToken"),
+		},
+	}, nil
+}
+
+func ensureMongoIndexes(ctx context.Context, DB *mongo.Database) error {
+	err := reslugDuplicateUrls(ctx, DB.Collection("Urls"))
+	if err != nil {
+		return err
+	}
+
+	// Slugs only need to be unique among live urls, deleted ones keep their
+	// slug but no longer reserve it.
+	_, err = DB.Collection("Urls").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "shortUrlSlug", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(
+				bson.M{"deleted": false},
+			),
+		},
+		{
+			Keys: bson.D{
+				{Key: "userId", Value: 1},
+				{Key: "deleted", Value: 1},
+				{Key: "createdAt", Value: -1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "userId", Value: 1},
+				{Key: "originalUrl", Value: 1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "deleted", Value: 1},
+				{Key: "deletedAt", Value: 1},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = DB.Collection("Visits").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "urlId", Value: 1},
+				{Key: "visitedAt", Value: 1},
+			},
+		},
+		// Rollups and the visit retention go through visits by time alone.
+		{Keys: bson.D{{Key: "visitedAt", Value: 1}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = DB.Collection("VisitRollups").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "urlId", Value: 1},
+			{Key: "period", Value: 1},
+			{Key: "start", Value: 1},
+			{Key: "dimension", Value: 1},
+			{Key: "value", Value: 1},
+			{Key: "bot", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = DB.Collection("UrlRevisions").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "urlId", Value: 1},
+			{Key: "changedAt", Value: -1},
+		},
+	})
+
+	return err
+}
+
+// reslugDuplicateUrls makes the slugs of live urls unique so the unique
+// slug index can be built. Slugs were not unique before it existed, and
+// only the oldest url of a slug was ever reached by it, so that one keeps
+// the slug and the others get their id appended to it.
+func reslugDuplicateUrls(ctx context.Context, urls *mongo.Collection) error {
+	cursor, err := urls.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"deleted": false}}},
+		{{Key: "$sort", Value: bson.D{
+			{Key: "createdAt", Value: 1},
+			{Key: "_id", Value: 1},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   "$shortUrlSlug",
+			"ids":   bson.M{"$push": "$_id"},
+			"count": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$match", Value: bson.M{"count": bson.M{"$gt": 1}}}},
+	})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var duplicates []struct {
+		Slug string               `bson:"_id"`
+		Ids  []primitive.ObjectID `bson:"ids"`
+	}
+	if err = cursor.All(ctx, &duplicates); err != nil {
+		return err
+	}
+
+	for _, duplicate := range duplicates {
+		for _, id := range duplicate.Ids[1:] {
+			slug := duplicate.Slug + "-" + id.Hex()
+
+			_, err = urls.UpdateByID(
+				ctx, id, bson.M{"$set": bson.M{"shortUrlSlug": slug}},
+			)
+			if err != nil {
+				return err
+			}
+
+			log.Printf(
+				"url %s shared the slug %q with an older url, it now has the "+
+					"slug %q and its QR code still points to the old one",
+				id.Hex(), duplicate.Slug, slug,
+			)
+		}
+	}
+
+	return nil
+}
+
+func insertedObjectID(res *mongo.InsertOneResult) (primitive.ObjectID, error) {
+	id, ok := res.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return primitive.NilObjectID, fmt.Errorf("unexpected type for InsertedID")
+	}
+
+	return id, nil
+}
+
+func mongoError(err error) error {
+	if err == mongo.ErrNoDocuments {
+		return models.ErrNotFound
+	}
+
+	if mongo.IsDuplicateKeyError(err) {
+		return models.ErrDuplicateKey
+	}
+
+	return err
+}
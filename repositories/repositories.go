@@ -0,0 +1,40 @@
+package repositories
+
+import (
+	"fmt"
+
+	"github.com/Origho-precious/url-shortener/go/configs"
+	"github.com/Origho-precious/url-shortener/go/models"
+)
+
+// Open returns the repositories for the storage backend selected by the
+// DB_DRIVER env, defaulting to MongoDB.
+func Open() (*models.Repositories, error) {
+	cfg, err := configs.LoadEnvs()
+	if err != nil {
+		return nil, fmt.Errorf("error loading env: %w", err)
+	}
+
+	switch cfg.DB_DRIVER {
+	case "", "mongo":
+		database, err := configs.ConnectDB()
+		if err != nil {
+			return nil, err
+		}
+
+		return NewMongoRepositories(database)
+	case "sqlite", "postgres":
+		db, err := configs.ConnectSQL()
+		if err != nil {
+			return nil, err
+		}
+
+		return NewSQLRepositories(db, cfg.DB_DRIVER)
+	case "memory":
+		fmt.Println("Using in-memory storage, data will not be persisted!")
+
+		return NewMemoryRepositories(), nil
+	default:
+		return nil, fmt.Errorf("unsupported DB_DRIVER: %s", cfg.DB_DRIVER)
+	}
+}
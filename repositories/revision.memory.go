@@ -0,0 +1,80 @@
+package repositories
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/Origho-precious/url-shortener/go/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryUrlRevisionRepository struct {
+	mu        sync.RWMutex
+	revisions map[primitive.ObjectID]models.UrlRevision
+}
+
+func (r *memoryUrlRevisionRepository) Insert(
+	_ context.Context, revision models.UrlRevision,
+) (primitive.ObjectID, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	revision.ID = primitive.NewObjectID()
+	r.revisions[revision.ID] = revision
+
+	return revision.ID, nil
+}
+
+func (r *memoryUrlRevisionRepository) FindByUrl(
+	_ context.Context, urlId primitive.ObjectID,
+) ([]models.UrlRevision, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var revisions []models.UrlRevision
+	for _, revision := range r.revisions {
+		if revision.UrlId == urlId {
+			revisions = append(revisions, revision)
+		}
+	}
+
+	sort.Slice(revisions, func(i, j int) bool {
+		if revisions[i].ChangedAt.Equal(revisions[j].ChangedAt) {
+			return revisions[i].ID.Hex() > revisions[j].ID.Hex()
+		}
+
+		return revisions[i].ChangedAt.After(revisions[j].ChangedAt)
+	})
+
+	return revisions, nil
+}
+
+func (r *memoryUrlRevisionRepository) FindByID(
+	_ context.Context, id primitive.ObjectID, urlId primitive.ObjectID,
+) (models.UrlRevision, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	revision, ok := r.revisions[id]
+	if !ok || revision.UrlId != urlId {
+		return models.UrlRevision{}, models.ErrNotFound
+	}
+
+	return revision, nil
+}
+
+func (r *memoryUrlRevisionRepository) DeleteByUrl(
+	_ context.Context, urlId primitive.ObjectID,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, revision := range r.revisions {
+		if revision.UrlId == urlId {
+			delete(r.revisions, id)
+		}
+	}
+
+	return nil
+}
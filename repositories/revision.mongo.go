@@ -0,0 +1,78 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/Origho-precious/url-shortener/go/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoUrlRevisionRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoUrlRevisionRepository) Insert(
+	ctx context.Context, revision models.UrlRevision,
+) (primitive.ObjectID, error) {
+	res, err := r.collection.InsertOne(ctx, bson.M{
+		"urlId":          revision.UrlId,
+		"changedBy":      revision.ChangedBy,
+		"changedAt":      revision.ChangedAt,
+		"oldOriginalUrl": revision.OldOriginalUrl,
+		"newOriginalUrl": revision.NewOriginalUrl,
+		"oldExpiresAt":   revision.OldExpiresAt,
+		"newExpiresAt":   revision.NewExpiresAt,
+	})
+	if err != nil {
+		return primitive.NilObjectID, mongoError(err)
+	}
+
+	return insertedObjectID(res)
+}
+
+func (r *mongoUrlRevisionRepository) FindByUrl(
+	ctx context.Context, urlId primitive.ObjectID,
+) ([]models.UrlRevision, error) {
+	opts := options.Find().SetSort(bson.D{
+		{Key: "changedAt", Value: -1},
+		{Key: "_id", Value: -1},
+	})
+
+	cursor, err := r.collection.Find(ctx, bson.M{"urlId": urlId}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var revisions []models.UrlRevision
+	if err = cursor.All(ctx, &revisions); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+func (r *mongoUrlRevisionRepository) FindByID(
+	ctx context.Context, id primitive.ObjectID, urlId primitive.ObjectID,
+) (models.UrlRevision, error) {
+	var revision models.UrlRevision
+
+	filter := bson.M{"_id": id, "urlId": urlId}
+	err := r.collection.FindOne(ctx, filter).Decode(&revision)
+	if err != nil {
+		return models.UrlRevision{}, mongoError(err)
+	}
+
+	return revision, nil
+}
+
+func (r *mongoUrlRevisionRepository) DeleteByUrl(
+	ctx context.Context, urlId primitive.ObjectID,
+) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"urlId": urlId})
+
+	return err
+}
@@ -0,0 +1,106 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/Origho-precious/url-shortener/go/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type sqlUrlRevisionRepository struct {
+	store *sqlStore
+}
+
+const urlRevisionColumns = `id, url_id, changed_by, changed_at,
+	old_original_url, new_original_url, old_expires_at, new_expires_at`
+
+func scanUrlRevision(row rowScanner) (models.UrlRevision, error) {
+	var revision models.UrlRevision
+	var id, urlId, changedBy string
+
+	err := row.Scan(
+		&id, &urlId, &changedBy, &revision.ChangedAt, &revision.OldOriginalUrl,
+		&revision.NewOriginalUrl, &revision.OldExpiresAt, &revision.NewExpiresAt,
+	)
+	if err != nil {
+		return models.UrlRevision{}, sqlError(err)
+	}
+
+	if revision.ID, err = parseObjectID(id); err != nil {
+		return models.UrlRevision{}, err
+	}
+
+	if revision.UrlId, err = parseObjectID(urlId); err != nil {
+		return models.UrlRevision{}, err
+	}
+
+	if revision.ChangedBy, err = parseObjectID(changedBy); err != nil {
+		return models.UrlRevision{}, err
+	}
+
+	return revision, nil
+}
+
+func (r *sqlUrlRevisionRepository) Insert(
+	ctx context.Context, revision models.UrlRevision,
+) (primitive.ObjectID, error) {
+	id := primitive.NewObjectID()
+
+	_, err := r.store.exec(ctx, `INSERT INTO url_revisions (`+urlRevisionColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		id.Hex(), revision.UrlId.Hex(), revision.ChangedBy.Hex(),
+		sqlTime(revision.ChangedAt), revision.OldOriginalUrl,
+		revision.NewOriginalUrl, sqlTime(revision.OldExpiresAt),
+		sqlTime(revision.NewExpiresAt),
+	)
+	if err != nil {
+		return primitive.NilObjectID, sqlError(err)
+	}
+
+	return id, nil
+}
+
+func (r *sqlUrlRevisionRepository) FindByUrl(
+	ctx context.Context, urlId primitive.ObjectID,
+) ([]models.UrlRevision, error) {
+	rows, err := r.store.query(ctx, `SELECT `+urlRevisionColumns+`
+		FROM url_revisions WHERE url_id = ?
+		ORDER BY changed_at DESC, id DESC`, urlId.Hex(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []models.UrlRevision
+	for rows.Next() {
+		revision, err := scanUrlRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		revisions = append(revisions, revision)
+	}
+
+	return revisions, rows.Err()
+}
+
+func (r *sqlUrlRevisionRepository) FindByID(
+	ctx context.Context, id primitive.ObjectID, urlId primitive.ObjectID,
+) (models.UrlRevision, error) {
+	row := r.store.queryRow(ctx, `SELECT `+urlRevisionColumns+`
+		FROM url_revisions WHERE id = ? AND url_id = ?`, id.Hex(), urlId.Hex(),
+	)
+
+	return scanUrlRevision(row)
+}
+
+func (r *sqlUrlRevisionRepository) DeleteByUrl(
+	ctx context.Context, urlId primitive.ObjectID,
+) error {
+	_, err := r.store.exec(
+		ctx, `DELETE FROM url_revisions WHERE url_id = ?`, urlId.Hex(),
+	)
+
+	return err
+}
@@ -0,0 +1,105 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Origho-precious/url-shortener/go/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryRollupKey struct {
+	urlId     primitive.ObjectID
+	period    string
+	start     int64
+	dimension string
+	value     string
+	bot       bool
+}
+
+type memoryVisitRollupRepository struct {
+	mu            sync.RWMutex
+	rollups       map[memoryRollupKey]models.VisitRollup
+	rolledUpUntil time.Time
+}
+
+func (r *memoryVisitRollupRepository) Upsert(
+	_ context.Context, rollups []models.VisitRollup,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, rollup := range rollups {
+		r.rollups[memoryRollupKey{
+			urlId:     rollup.UrlId,
+			period:    rollup.Period,
+			start:     rollup.Start.Unix(),
+			dimension: rollup.Dimension,
+			value:     rollup.Value,
+			bot:       rollup.Bot,
+		}] = rollup
+	}
+
+	return nil
+}
+
+func (r *memoryVisitRollupRepository) Find(
+	_ context.Context, filter models.VisitFilter, period string,
+	dimension string,
+) ([]models.VisitRollup, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	urlIds := make(map[primitive.ObjectID]bool, len(filter.UrlIds))
+	for _, urlId := range filter.UrlIds {
+		urlIds[urlId] = true
+	}
+
+	var rollups []models.VisitRollup
+	for _, rollup := range r.rollups {
+		if urlIds[rollup.UrlId] && rollup.Period == period &&
+			rollup.Dimension == dimension &&
+			!rollup.Start.Before(filter.From) && rollup.Start.Before(filter.To) &&
+			(!rollup.Bot || filter.IncludeBots) {
+			rollups = append(rollups, rollup)
+		}
+	}
+
+	return rollups, nil
+}
+
+func (r *memoryVisitRollupRepository) DeleteByUrl(
+	_ context.Context, urlId primitive.ObjectID,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for key := range r.rollups {
+		if key.urlId == urlId {
+			delete(r.rollups, key)
+		}
+	}
+
+	return nil
+}
+
+func (r *memoryVisitRollupRepository) RolledUpUntil(_ context.Context) (
+	time.Time, error,
+) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.rolledUpUntil, nil
+}
+
+func (r *memoryVisitRollupRepository) SetRolledUpUntil(
+	_ context.Context, until time.Time,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.rolledUpUntil = until
+
+	return nil
+}
@@ -0,0 +1,128 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/Origho-precious/url-shortener/go/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// mongoVisitRollupRepository keeps how far visits have been rolled up in a
+// single document of the progress collection.
+type mongoVisitRollupRepository struct {
+	collection *mongo.Collection
+	progress   *mongo.Collection
+}
+
+const mongoRollupProgressId = "visits"
+
+func (r *mongoVisitRollupRepository) Upsert(
+	ctx context.Context, rollups []models.VisitRollup,
+) error {
+	if len(rollups) == 0 {
+		return nil
+	}
+
+	writes := make([]mongo.WriteModel, 0, len(rollups))
+	for _, rollup := range rollups {
+		key := bson.M{
+			"urlId":     rollup.UrlId,
+			"period":    rollup.Period,
+			"start":     rollup.Start,
+			"dimension": rollup.Dimension,
+			"value":     rollup.Value,
+			"bot":       rollup.Bot,
+		}
+
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(key).
+			SetUpdate(bson.M{"$set": bson.M{
+				"clicks":         rollup.Clicks,
+				"uniqueVisitors": rollup.UniqueVisitors,
+			}}).
+			SetUpsert(true),
+		)
+	}
+
+	opts := options.BulkWrite().SetOrdered(false)
+	_, err := r.collection.BulkWrite(ctx, writes, opts)
+
+	return err
+}
+
+func (r *mongoVisitRollupRepository) Find(
+	ctx context.Context, filter models.VisitFilter, period string,
+	dimension string,
+) ([]models.VisitRollup, error) {
+	if len(filter.UrlIds) == 0 {
+		return nil, nil
+	}
+
+	query := bson.M{
+		"urlId":     bson.M{"$in": filter.UrlIds},
+		"period":    period,
+		"start":     bson.M{"$gte": filter.From, "$lt": filter.To},
+		"dimension": dimension,
+	}
+	if !filter.IncludeBots {
+		query["bot"] = false
+	}
+
+	cursor, err := r.collection.Find(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rollups []models.VisitRollup
+	if err = cursor.All(ctx, &rollups); err != nil {
+		return nil, err
+	}
+
+	return rollups, nil
+}
+
+func (r *mongoVisitRollupRepository) DeleteByUrl(
+	ctx context.Context, urlId primitive.ObjectID,
+) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"urlId": urlId})
+
+	return err
+}
+
+func (r *mongoVisitRollupRepository) RolledUpUntil(ctx context.Context) (
+	time.Time, error,
+) {
+	var progress struct {
+		Until time.Time
+	}
+
+	err := r.progress.FindOne(
+		ctx, bson.M{"_id": mongoRollupProgressId},
+	).Decode(&progress)
+	if err == mongo.ErrNoDocuments {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return progress.Until, nil
+}
+
+func (r *mongoVisitRollupRepository) SetRolledUpUntil(
+	ctx context.Context, until time.Time,
+) error {
+	_, err := r.progress.UpdateOne(
+		ctx,
+		bson.M{"_id": mongoRollupProgressId},
+		bson.M{"$set": bson.M{"until": until}},
+		options.Update().SetUpsert(true),
+	)
+
+	return err
+}
@@ -0,0 +1,139 @@
+package repositories
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/Origho-precious/url-shortener/go/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type sqlVisitRollupRepository struct {
+	store *sqlStore
+}
+
+const sqlRollupProgressName = "visits"
+
+func (r *sqlVisitRollupRepository) Upsert(
+	ctx context.Context, rollups []models.VisitRollup,
+) error {
+	tx, err := r.store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := r.store.rebind(`INSERT INTO visit_rollups (url_id, period,
+		period_start, dimension, value, bot, clicks, unique_visitors)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (url_id, period, period_start, dimension, value, bot)
+		DO UPDATE SET clicks = excluded.clicks,
+			unique_visitors = excluded.unique_visitors`)
+
+	for _, rollup := range rollups {
+		_, err = tx.ExecContext(ctx, query,
+			rollup.UrlId.Hex(), rollup.Period, sqlTime(rollup.Start),
+			rollup.Dimension, rollup.Value, rollup.Bot, rollup.Clicks,
+			rollup.UniqueVisitors,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *sqlVisitRollupRepository) Find(
+	ctx context.Context, filter models.VisitFilter, period string,
+	dimension string,
+) ([]models.VisitRollup, error) {
+	if len(filter.UrlIds) == 0 {
+		return nil, nil
+	}
+
+	placeholders := strings.Repeat("?, ", len(filter.UrlIds)-1) + "?"
+	args := []any{period, dimension, sqlTime(filter.From), sqlTime(filter.To)}
+	for _, urlId := range filter.UrlIds {
+		args = append(args, urlId.Hex())
+	}
+
+	query := `SELECT url_id, period_start, value, bot, clicks, unique_visitors
+		FROM visit_rollups
+		WHERE period = ? AND dimension = ?
+			AND period_start >= ? AND period_start < ?
+			AND url_id IN (` + placeholders + `)`
+	if !filter.IncludeBots {
+		query += ` AND bot = ?`
+		args = append(args, false)
+	}
+
+	rows, err := r.store.query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rollups []models.VisitRollup
+	for rows.Next() {
+		rollup := models.VisitRollup{Period: period, Dimension: dimension}
+		var urlId string
+
+		err = rows.Scan(
+			&urlId, &rollup.Start, &rollup.Value, &rollup.Bot, &rollup.Clicks,
+			&rollup.UniqueVisitors,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if rollup.UrlId, err = parseObjectID(urlId); err != nil {
+			return nil, err
+		}
+
+		rollups = append(rollups, rollup)
+	}
+
+	return rollups, rows.Err()
+}
+
+func (r *sqlVisitRollupRepository) DeleteByUrl(
+	ctx context.Context, urlId primitive.ObjectID,
+) error {
+	_, err := r.store.exec(ctx, `DELETE FROM visit_rollups WHERE url_id = ?`,
+		urlId.Hex(),
+	)
+
+	return err
+}
+
+func (r *sqlVisitRollupRepository) RolledUpUntil(ctx context.Context) (
+	time.Time, error,
+) {
+	var until time.Time
+
+	err := r.store.queryRow(ctx, `SELECT until_at FROM rollup_progress
+		WHERE name = ?`, sqlRollupProgressName,
+	).Scan(&until)
+	if err != nil {
+		if err = sqlError(err); err == models.ErrNotFound {
+			return time.Time{}, nil
+		}
+
+		return time.Time{}, err
+	}
+
+	return until, nil
+}
+
+func (r *sqlVisitRollupRepository) SetRolledUpUntil(
+	ctx context.Context, until time.Time,
+) error {
+	_, err := r.store.exec(ctx, `INSERT INTO rollup_progress (name, until_at)
+		VALUES (?, ?) ON CONFLICT (name) DO UPDATE SET until_at = excluded.until_at`,
+		sqlRollupProgressName, sqlTime(until),
+	)
+
+	return err
+}
@@ -0,0 +1,144 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Origho-precious/url-shortener/go/models"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// sqlStore wraps a *sql.DB and hides the differences between the SQLite and
+// Postgres dialects. Queries are written with "?" placeholders and rebound
+// for Postgres.
+type sqlStore struct {
+	db      *sql.DB
+	dialect string
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// NewSQLRepositories runs any pending schema migrations and returns
+// repositories backed by db. dialect is either "sqlite" or "postgres".
+func NewSQLRepositories(db *sql.DB, dialect string) (*models.Repositories, error) {
+	store := &sqlStore{db: db, dialect: dialect}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	err := store.migrate(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error migrating the database: %w", err)
+	}
+
+	return &models.Repositories{
+		Urls:               &sqlUrlRepository{store: store},
+		Visits:             &sqlVisitRepository{store: store},
+		VisitRollups:       &sqlVisitRollupRepository{store: store},
+		VisitorSalts:       &sqlVisitorSaltRepository{store: store},
+		UrlRevisions:       &sqlUrlRevisionRepository{store: store},
+		Counters:           &sqlCounterRepository{store: store},
+		Users:              &sqlUserRepository{store: store},
+		ForgotPasswords:    &sqlForgotPasswordRepository{store: store},
+		VerificationTokens: &sqlVerificationTokenRepository{store: store},
+	}, nil
+}
+
+func (s *sqlStore) rebind(query string) string {
+	if s.dialect != "postgres" {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, char := range query {
+		if char == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+
+		b.WriteRune(char)
+	}
+
+	return b.String()
+}
+
+func (s *sqlStore) exec(ctx context.Context, query string, args ...any) (
+	sql.Result, error,
+) {
+	return s.db.ExecContext(ctx, s.rebind(query), args...)
+}
+
+func (s *sqlStore) query(ctx context.Context, query string, args ...any) (
+	*sql.Rows, error,
+) {
+	return s.db.QueryContext(ctx, s.rebind(query), args...)
+}
+
+func (s *sqlStore) queryRow(ctx context.Context, query string, args ...any) *sql.Row {
+	return s.db.QueryRowContext(ctx, s.rebind(query), args...)
+}
+
+// execOne runs an UPDATE or DELETE and reports models.ErrNotFound when no row
+// matched.
+func (s *sqlStore) execOne(ctx context.Context, query string, args ...any) error {
+	res, err := s.exec(ctx, query, args...)
+	if err != nil {
+		return sqlError(err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return models.ErrNotFound
+	}
+
+	return nil
+}
+
+func sqlError(err error) error {
+	if err == sql.ErrNoRows {
+		return models.ErrNotFound
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return models.ErrDuplicateKey
+	}
+
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) &&
+		sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE {
+		return models.ErrDuplicateKey
+	}
+
+	return err
+}
+
+// sqlTime normalises times to UTC so they sort and compare consistently on
+// both dialects.
+func sqlTime(t time.Time) time.Time {
+	return t.UTC()
+}
+
+func parseObjectID(hex string) (primitive.ObjectID, error) {
+	if hex == "" {
+		return primitive.NilObjectID, nil
+	}
+
+	return primitive.ObjectIDFromHex(hex)
+}
@@ -0,0 +1,330 @@
+package repositories
+
+import (
+	"context"
+	"regexp"
+	"time"
+)
+
+type sqlMigration struct {
+	version    int
+	name       string
+	statements []string
+}
+
+// sqlMigrations is the versioned schema history. Migrations are applied in
+// order and recorded in schema_migrations; never edit one that has shipped,
+// append a new version instead.
+//
+// Statements use SQLite type names. TIMESTAMP is rewritten to TIMESTAMPTZ on
+// Postgres.
+var sqlMigrations = []sqlMigration{
+	{
+		version: 1,
+		name:    "create initial tables",
+		statements: []string{
+			`CREATE TABLE users (
+				id TEXT PRIMARY KEY,
+				email TEXT NOT NULL,
+				full_name TEXT NOT NULL,
+				password TEXT NOT NULL,
+				created_at TIMESTAMP NOT NULL,
+				email_verified BOOLEAN NOT NULL DEFAULT FALSE
+			)`,
+			`CREATE UNIQUE INDEX users_email_key ON users (email)`,
+			`CREATE TABLE urls (
+				id TEXT PRIMARY KEY,
+				user_id TEXT NOT NULL,
+				deleted BOOLEAN NOT NULL DEFAULT FALSE,
+				created_at TIMESTAMP NOT NULL,
+				expires_at TIMESTAMP NOT NULL,
+				visit_count BIGINT NOT NULL DEFAULT 0,
+				custom_alias BOOLEAN NOT NULL DEFAULT FALSE,
+				original_url TEXT NOT NULL,
+				short_url_slug TEXT NOT NULL,
+				last_visited_at TIMESTAMP NOT NULL,
+				qr_code_image_url TEXT NOT NULL DEFAULT ''
+			)`,
+			`CREATE UNIQUE INDEX urls_short_url_slug_key ON urls (short_url_slug)`,
+			`CREATE INDEX urls_user_id_created_at_idx
+				ON urls (user_id, deleted, created_at DESC)`,
+			`CREATE TABLE visits (
+				id TEXT PRIMARY KEY,
+				url_id TEXT NOT NULL,
+				browser TEXT NOT NULL DEFAULT '',
+				location TEXT NOT NULL DEFAULT '',
+				referrer TEXT NOT NULL DEFAULT '',
+				ip_address TEXT NOT NULL DEFAULT '',
+				visited_at TIMESTAMP NOT NULL,
+				device_type TEXT NOT NULL DEFAULT ''
+			)`,
+			`CREATE INDEX visits_url_id_visited_at_idx ON visits (url_id, visited_at)`,
+			`CREATE TABLE forgot_passwords (
+				id TEXT PRIMARY KEY,
+				user_email TEXT NOT NULL,
+				created_at TIMESTAMP NOT NULL
+			)`,
+			`CREATE TABLE verification_tokens (
+				id TEXT PRIMARY KEY,
+				token TEXT NOT NULL,
+				user_id TEXT NOT NULL,
+				created_at TIMESTAMP NOT NULL
+			)`,
+			`CREATE INDEX verification_tokens_user_id_idx
+				ON verification_tokens (user_id)`,
+		},
+	},
+	{
+		version: 2,
+		name:    "scope slug uniqueness to non-deleted urls",
+		statements: []string{
+			`DROP INDEX urls_short_url_slug_key`,
+			`CREATE UNIQUE INDEX urls_short_url_slug_key
+				ON urls (short_url_slug) WHERE deleted = FALSE`,
+		},
+	},
+	{
+		version: 3,
+		name:    "create counters table",
+		statements: []string{
+			`CREATE TABLE counters (
+				name TEXT PRIMARY KEY,
+				value BIGINT NOT NULL
+			)`,
+		},
+	},
+	{
+		version: 4,
+		name:    "index urls by original url",
+		statements: []string{
+			`CREATE INDEX urls_user_id_original_url_idx
+				ON urls (user_id, original_url)`,
+		},
+	},
+	{
+		version: 5,
+		name:    "create url revisions table",
+		statements: []string{
+			`CREATE TABLE url_revisions (
+				id TEXT PRIMARY KEY,
+				url_id TEXT NOT NULL,
+				changed_by TEXT NOT NULL,
+				changed_at TIMESTAMP NOT NULL,
+				old_original_url TEXT NOT NULL,
+				new_original_url TEXT NOT NULL,
+				old_expires_at TIMESTAMP NOT NULL,
+				new_expires_at TIMESTAMP NOT NULL
+			)`,
+			`CREATE INDEX url_revisions_url_id_changed_at_idx
+				ON url_revisions (url_id, changed_at DESC)`,
+		},
+	},
+	{
+		version: 6,
+		name:    "track trashed urls and qr code files",
+		statements: []string{
+			`ALTER TABLE urls ADD COLUMN deleted_at TIMESTAMP NOT NULL
+				DEFAULT '0001-01-01 00:00:00+00:00'`,
+			`ALTER TABLE urls ADD COLUMN qr_code_file_id TEXT NOT NULL DEFAULT ''`,
+			`CREATE INDEX urls_deleted_deleted_at_idx ON urls (deleted, deleted_at)`,
+		},
+	},
+	{
+		version: 7,
+		name:    "add url passwords",
+		statements: []string{
+			`ALTER TABLE urls ADD COLUMN password_hash TEXT NOT NULL DEFAULT ''`,
+		},
+	},
+	{
+		version: 8,
+		name:    "add url click limits",
+		statements: []string{
+			`ALTER TABLE urls ADD COLUMN max_clicks BIGINT NOT NULL DEFAULT 0`,
+		},
+	},
+	{
+		version: 9,
+		name:    "add url activation times and fallback urls",
+		statements: []string{
+			`ALTER TABLE urls ADD COLUMN activates_at TIMESTAMP NOT NULL
+				DEFAULT '0001-01-01 00:00:00+00:00'`,
+			`ALTER TABLE urls ADD COLUMN expired_redirect_url TEXT NOT NULL
+				DEFAULT ''`,
+			`ALTER TABLE urls ADD COLUMN inactive_redirect_url TEXT NOT NULL
+				DEFAULT ''`,
+		},
+	},
+	{
+		version: 10,
+		name:    "add url and account fallback urls",
+		statements: []string{
+			`ALTER TABLE urls ADD COLUMN fallback_url TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE users ADD COLUMN fallback_url TEXT NOT NULL DEFAULT ''`,
+			`CREATE INDEX urls_short_url_slug_deleted_at_idx
+				ON urls (short_url_slug, deleted_at)`,
+		},
+	},
+	{
+		version: 11,
+		name:    "add url targeting rules",
+		statements: []string{
+			`ALTER TABLE urls ADD COLUMN targeting_rules TEXT NOT NULL DEFAULT '[]'`,
+		},
+	},
+	{
+		version: 12,
+		name:    "add visit country",
+		statements: []string{
+			`ALTER TABLE visits ADD COLUMN country TEXT NOT NULL DEFAULT ''`,
+		},
+	},
+	{
+		version: 13,
+		name:    "add split destinations",
+		statements: []string{
+			`ALTER TABLE urls ADD COLUMN split_destinations TEXT NOT NULL DEFAULT '[]'`,
+			`ALTER TABLE urls ADD COLUMN sticky_split BOOLEAN NOT NULL DEFAULT FALSE`,
+			`ALTER TABLE visits ADD COLUMN variant TEXT NOT NULL DEFAULT ''`,
+		},
+	},
+	{
+		version: 14,
+		name:    "add utm parameters and query passthrough",
+		statements: []string{
+			`ALTER TABLE urls ADD COLUMN utm_source TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE urls ADD COLUMN utm_medium TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE urls ADD COLUMN utm_campaign TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE urls ADD COLUMN utm_term TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE urls ADD COLUMN utm_content TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE urls ADD COLUMN query_passthrough TEXT NOT NULL DEFAULT ''`,
+		},
+	},
+	{
+		version: 15,
+		name:    "add link previews",
+		statements: []string{
+			`ALTER TABLE urls ADD COLUMN preview BOOLEAN NOT NULL DEFAULT FALSE`,
+			`ALTER TABLE urls ADD COLUMN og_title TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE urls ADD COLUMN og_description TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE urls ADD COLUMN og_image_url TEXT NOT NULL DEFAULT ''`,
+		},
+	},
+	{
+		version: 16,
+		name:    "count bot visits separately",
+		statements: []string{
+			`ALTER TABLE urls ADD COLUMN bot_visit_count BIGINT NOT NULL DEFAULT 0`,
+			`ALTER TABLE visits ADD COLUMN bot BOOLEAN NOT NULL DEFAULT FALSE`,
+		},
+	},
+	{
+		version: 17,
+		name:    "store parsed user agents on visits",
+		statements: []string{
+			`ALTER TABLE visits ADD COLUMN browser_version TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE visits ADD COLUMN os TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE visits ADD COLUMN os_version TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE visits ADD COLUMN vendor TEXT NOT NULL DEFAULT ''`,
+		},
+	},
+	{
+		version: 18,
+		name:    "hash visitors",
+		statements: []string{
+			`ALTER TABLE visits ADD COLUMN visitor_hash TEXT NOT NULL DEFAULT ''`,
+			`CREATE TABLE visitor_salts (
+				day TEXT PRIMARY KEY,
+				salt TEXT NOT NULL
+			)`,
+		},
+	},
+	{
+		version: 19,
+		name:    "roll up visits",
+		statements: []string{
+			`CREATE TABLE visit_rollups (
+				url_id TEXT NOT NULL,
+				period TEXT NOT NULL,
+				period_start TIMESTAMP NOT NULL,
+				dimension TEXT NOT NULL,
+				value TEXT NOT NULL,
+				bot BOOLEAN NOT NULL,
+				clicks BIGINT NOT NULL,
+				unique_visitors BIGINT NOT NULL,
+				PRIMARY KEY (url_id, period, period_start, dimension, value, bot)
+			)`,
+			`CREATE TABLE rollup_progress (
+				name TEXT PRIMARY KEY,
+				until_at TIMESTAMP NOT NULL
+			)`,
+			`CREATE INDEX visits_visited_at_idx ON visits (visited_at)`,
+		},
+	},
+}
+
+var timestampType = regexp.MustCompile(`\bTIMESTAMP\b`)
+
+func (s *sqlStore) ddl(statement string) string {
+	if s.dialect == "postgres" {
+		return timestampType.ReplaceAllString(statement, "TIMESTAMPTZ")
+	}
+
+	return statement
+}
+
+func (s *sqlStore) migrate(ctx context.Context) error {
+	_, err := s.exec(ctx, s.ddl(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL
+	)`))
+	if err != nil {
+		return err
+	}
+
+	var current int
+	err = s.queryRow(
+		ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`,
+	).Scan(&current)
+	if err != nil {
+		return err
+	}
+
+	for _, migration := range sqlMigrations {
+		if migration.version <= current {
+			continue
+		}
+
+		err = s.applyMigration(ctx, migration)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *sqlStore) applyMigration(ctx context.Context, migration sqlMigration) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, statement := range migration.statements {
+		_, err = tx.ExecContext(ctx, s.ddl(statement))
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, s.rebind(
+		`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+	), migration.version, migration.name, sqlTime(time.Now()))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,560 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Origho-precious/url-shortener/go/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryUrlRepository struct {
+	mu   sync.RWMutex
+	urls map[primitive.ObjectID]models.Url
+}
+
+type memoryVisitRepository struct {
+	mu     sync.RWMutex
+	visits map[primitive.ObjectID]models.Visit
+}
+
+func (r *memoryUrlRepository) Insert(_ context.Context, url models.Url) (
+	primitive.ObjectID, error,
+) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !url.Deleted && r.slugTaken(url.ShortUrlSlug) {
+		return primitive.NilObjectID, models.ErrDuplicateKey
+	}
+
+	url.ID = primitive.NewObjectID()
+	r.urls[url.ID] = url
+
+	return url.ID, nil
+}
+
+// slugTaken mirrors the partial unique index the other backends keep on the
+// slugs of non-deleted urls.
+func (r *memoryUrlRepository) slugTaken(slug string) bool {
+	for _, url := range r.urls {
+		if url.ShortUrlSlug == slug && !url.Deleted {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *memoryUrlRepository) FindBySlug(_ context.Context, slug string) (
+	models.Url, error,
+) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, url := range r.urls {
+		if url.ShortUrlSlug == slug && !url.Deleted {
+			return url, nil
+		}
+	}
+
+	return models.Url{}, models.ErrNotFound
+}
+
+func (r *memoryUrlRepository) FindDeletedBySlug(
+	_ context.Context, slug string,
+) (models.Url, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var latest models.Url
+	found := false
+	for _, url := range r.urls {
+		if url.ShortUrlSlug != slug || !url.Deleted {
+			continue
+		}
+
+		if !found || url.DeletedAt.After(latest.DeletedAt) {
+			latest = url
+			found = true
+		}
+	}
+
+	if !found {
+		return models.Url{}, models.ErrNotFound
+	}
+
+	return latest, nil
+}
+
+func (r *memoryUrlRepository) FindByID(
+	_ context.Context, id primitive.ObjectID, userId primitive.ObjectID,
+	deleted bool,
+) (models.Url, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	url, ok := r.urls[id]
+	if !ok || url.UserId != userId || url.Deleted != deleted {
+		return models.Url{}, models.ErrNotFound
+	}
+
+	return url, nil
+}
+
+func (r *memoryUrlRepository) Update(_ context.Context, url models.Url) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.urls[url.ID]
+	if !ok || existing.UserId != url.UserId || existing.Deleted {
+		return models.ErrNotFound
+	}
+
+	if url.ShortUrlSlug != existing.ShortUrlSlug && r.slugTaken(url.ShortUrlSlug) {
+		return models.ErrDuplicateKey
+	}
+
+	existing.ExpiresAt = url.ExpiresAt
+	existing.CustomAlias = url.CustomAlias
+	existing.OriginalUrl = url.OriginalUrl
+	existing.ShortUrlSlug = url.ShortUrlSlug
+	existing.QRCodeImageUrl = url.QRCodeImageUrl
+	existing.QRCodeFileId = url.QRCodeFileId
+	existing.PasswordHash = url.PasswordHash
+	existing.MaxClicks = url.MaxClicks
+	existing.ActivatesAt = url.ActivatesAt
+	existing.ExpiredRedirectUrl = url.ExpiredRedirectUrl
+	existing.InactiveRedirectUrl = url.InactiveRedirectUrl
+	existing.FallbackUrl = url.FallbackUrl
+	existing.TargetingRules = url.TargetingRules
+	existing.SplitDestinations = url.SplitDestinations
+	existing.StickySplit = url.StickySplit
+	existing.UtmSource = url.UtmSource
+	existing.UtmMedium = url.UtmMedium
+	existing.UtmCampaign = url.UtmCampaign
+	existing.UtmTerm = url.UtmTerm
+	existing.UtmContent = url.UtmContent
+	existing.QueryPassthrough = url.QueryPassthrough
+	existing.Preview = url.Preview
+	existing.OGTitle = url.OGTitle
+	existing.OGDescription = url.OGDescription
+	existing.OGImageUrl = url.OGImageUrl
+	r.urls[url.ID] = existing
+
+	return nil
+}
+
+func (r *memoryUrlRepository) FindActiveByOriginalUrl(
+	_ context.Context, userId primitive.ObjectID, originalUrl string,
+	now time.Time,
+) ([]models.Url, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var urls []models.Url
+	for _, url := range r.byUser(userId, false) {
+		if url.OriginalUrl != originalUrl || !hasClicksLeft(url) ||
+			url.ActivatesAt.After(now) {
+			continue
+		}
+
+		if url.ExpiresAt.IsZero() || url.ExpiresAt.After(now) {
+			urls = append(urls, url)
+		}
+	}
+
+	return urls, nil
+}
+
+func (r *memoryUrlRepository) SetQRCode(
+	_ context.Context, id primitive.ObjectID, qrCodeImageUrl string,
+	qrCodeFileId string,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	url, ok := r.urls[id]
+	if !ok {
+		return models.ErrNotFound
+	}
+
+	url.QRCodeImageUrl = qrCodeImageUrl
+	url.QRCodeFileId = qrCodeFileId
+	r.urls[id] = url
+
+	return nil
+}
+
+func (r *memoryUrlRepository) Delete(_ context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.urls[id]; !ok {
+		return models.ErrNotFound
+	}
+
+	delete(r.urls, id)
+
+	return nil
+}
+
+// byUser returns the user's urls that are in the trash or not, newest
+// first.
+func (r *memoryUrlRepository) byUser(
+	userId primitive.ObjectID, deleted bool,
+) []models.Url {
+	var urls []models.Url
+	for _, url := range r.urls {
+		if url.UserId == userId && url.Deleted == deleted {
+			urls = append(urls, url)
+		}
+	}
+
+	sort.Slice(urls, func(i, j int) bool {
+		if urls[i].CreatedAt.Equal(urls[j].CreatedAt) {
+			return urls[i].ID.Hex() > urls[j].ID.Hex()
+		}
+
+		return urls[i].CreatedAt.After(urls[j].CreatedAt)
+	})
+
+	return urls
+}
+
+func (r *memoryUrlRepository) FindByUser(
+	_ context.Context, userId primitive.ObjectID, deleted bool, skip int64,
+	limit int64,
+) ([]models.Url, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	urls := r.byUser(userId, deleted)
+
+	return paginate(urls, skip, limit), nil
+}
+
+func (r *memoryUrlRepository) CountByUser(
+	_ context.Context, userId primitive.ObjectID, deleted bool,
+) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return int64(len(r.byUser(userId, deleted))), nil
+}
+
+func (r *memoryUrlRepository) SoftDelete(
+	_ context.Context, id primitive.ObjectID, userId primitive.ObjectID,
+	deletedAt time.Time,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	url, ok := r.urls[id]
+	if !ok || url.UserId != userId || url.Deleted {
+		return models.ErrNotFound
+	}
+
+	url.Deleted = true
+	url.DeletedAt = deletedAt
+	r.urls[id] = url
+
+	return nil
+}
+
+func (r *memoryUrlRepository) Restore(
+	_ context.Context, id primitive.ObjectID, userId primitive.ObjectID,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	url, ok := r.urls[id]
+	if !ok || url.UserId != userId || !url.Deleted {
+		return models.ErrNotFound
+	}
+
+	if r.slugTaken(url.ShortUrlSlug) {
+		return models.ErrDuplicateKey
+	}
+
+	url.Deleted = false
+	url.DeletedAt = time.Time{}
+	r.urls[id] = url
+
+	return nil
+}
+
+func (r *memoryUrlRepository) FindDeletedBefore(
+	_ context.Context, cutoff time.Time, limit int64,
+) ([]models.Url, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var urls []models.Url
+	for _, url := range r.urls {
+		if url.Deleted && !url.DeletedAt.IsZero() && url.DeletedAt.Before(cutoff) {
+			urls = append(urls, url)
+		}
+	}
+
+	sort.Slice(urls, func(i, j int) bool {
+		return urls[i].DeletedAt.Before(urls[j].DeletedAt)
+	})
+
+	return paginate(urls, 0, limit), nil
+}
+
+func (r *memoryUrlRepository) IncrementVisitCount(
+	_ context.Context, id primitive.ObjectID, visitedAt time.Time,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	url, ok := r.urls[id]
+	if !ok || url.Deleted || !hasClicksLeft(url) {
+		return models.ErrNotFound
+	}
+
+	url.VisitCount++
+	url.LastVisitedAt = visitedAt
+	r.urls[id] = url
+
+	return nil
+}
+
+func (r *memoryUrlRepository) IncrementBotVisitCount(
+	_ context.Context, id primitive.ObjectID,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	url, ok := r.urls[id]
+	if !ok || url.Deleted {
+		return models.ErrNotFound
+	}
+
+	url.BotVisitCount++
+	r.urls[id] = url
+
+	return nil
+}
+
+func (r *memoryVisitRepository) Insert(_ context.Context, visit models.Visit) (
+	primitive.ObjectID, error,
+) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	visit.ID = primitive.NewObjectID()
+	r.visits[visit.ID] = visit
+
+	return visit.ID, nil
+}
+
+func (r *memoryVisitRepository) CountBy(
+	_ context.Context, filter models.VisitFilter, dimension string,
+) ([]models.VisitCount, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	urlIds := make(map[primitive.ObjectID]bool, len(filter.UrlIds))
+	for _, urlId := range filter.UrlIds {
+		urlIds[urlId] = true
+	}
+
+	counts := make(map[string]int64)
+	visitors := make(map[string]map[string]bool)
+	for _, visit := range r.visits {
+		if !urlIds[visit.UrlId] || visit.VisitedAt.Before(filter.From) ||
+			!visit.VisitedAt.Before(filter.To) ||
+			(visit.Bot && !filter.IncludeBots) {
+			continue
+		}
+
+		value, err := memoryVisitValue(visit, dimension)
+		if err != nil {
+			return nil, err
+		}
+
+		if visitors[value] == nil {
+			visitors[value] = make(map[string]bool)
+		}
+
+		counts[value]++
+		visitors[value][memoryVisitor(visit)] = true
+	}
+
+	var visitCounts []models.VisitCount
+	for value, count := range counts {
+		visitCounts = append(visitCounts, models.VisitCount{
+			Value:    value,
+			Count:    count,
+			Visitors: int64(len(visitors[value])),
+		})
+	}
+
+	return visitCounts, nil
+}
+
+func (r *memoryVisitRepository) CountVisitors(
+	_ context.Context, from time.Time, to time.Time, dimension string,
+) ([]models.VisitorCount, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	type countKey struct {
+		urlId primitive.ObjectID
+		bot   bool
+		value string
+	}
+
+	counts := make(map[countKey]int64)
+	visitors := make(map[countKey]map[string]bool)
+	for _, visit := range r.visits {
+		if visit.VisitedAt.Before(from) || !visit.VisitedAt.Before(to) {
+			continue
+		}
+
+		value, err := memoryVisitValue(visit, dimension)
+		if err != nil {
+			return nil, err
+		}
+
+		key := countKey{urlId: visit.UrlId, bot: visit.Bot, value: value}
+		if visitors[key] == nil {
+			visitors[key] = make(map[string]bool)
+		}
+
+		counts[key]++
+		visitors[key][memoryVisitor(visit)] = true
+	}
+
+	var visitorCounts []models.VisitorCount
+	for key, count := range counts {
+		visitorCounts = append(visitorCounts, models.VisitorCount{
+			UrlId:    key.urlId,
+			Bot:      key.bot,
+			Value:    key.value,
+			Clicks:   count,
+			Visitors: int64(len(visitors[key])),
+		})
+	}
+
+	return visitorCounts, nil
+}
+
+// memoryVisitValue returns the value of dimension for visit.
+func memoryVisitValue(visit models.Visit, dimension string) (string, error) {
+	switch dimension {
+	case models.VisitHour:
+		return visit.VisitedAt.UTC().Format(models.VisitHourLayout), nil
+	case models.VisitDay:
+		return visit.VisitedAt.UTC().Format(models.VisitDayLayout), nil
+	case models.VisitUrl:
+		return visit.UrlId.Hex(), nil
+	case models.VisitReferrer:
+		return visit.Referrer, nil
+	case models.VisitCountry:
+		return visit.Country, nil
+	case models.VisitDevice:
+		return visit.DeviceType, nil
+	case models.VisitBrowser:
+		return visit.Browser, nil
+	case models.VisitLocation:
+		return visit.Location, nil
+	}
+
+	return "", fmt.Errorf("unknown visit dimension %q", dimension)
+}
+
+// memoryVisitor identifies the visitor of a visit the way CountBy tells
+// visitors apart.
+func memoryVisitor(visit models.Visit) string {
+	if visit.VisitorHash != "" {
+		return visit.VisitorHash
+	}
+
+	if visit.IPAddress != "" {
+		return visit.IPAddress
+	}
+
+	return visit.ID.Hex()
+}
+
+func (r *memoryVisitRepository) FirstVisitedAt(_ context.Context) (
+	time.Time, error,
+) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var first time.Time
+	for _, visit := range r.visits {
+		if first.IsZero() || visit.VisitedAt.Before(first) {
+			first = visit.VisitedAt
+		}
+	}
+
+	if first.IsZero() {
+		return time.Time{}, models.ErrNotFound
+	}
+
+	return first, nil
+}
+
+func (r *memoryVisitRepository) DeleteBefore(
+	_ context.Context, cutoff time.Time,
+) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for id, visit := range r.visits {
+		if visit.VisitedAt.Before(cutoff) {
+			delete(r.visits, id)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+func (r *memoryVisitRepository) DeleteByUrl(
+	_ context.Context, urlId primitive.ObjectID,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, visit := range r.visits {
+		if visit.UrlId == urlId {
+			delete(r.visits, id)
+		}
+	}
+
+	return nil
+}
+
+func hasClicksLeft(url models.Url) bool {
+	return url.MaxClicks == 0 || url.VisitCount < url.MaxClicks
+}
+
+// paginate mirrors Mongo's skip/limit semantics, where a limit of 0 means
+// no limit.
+func paginate[T any](records []T, skip int64, limit int64) []T {
+	if skip < 0 {
+		skip = 0
+	}
+
+	if skip >= int64(len(records)) {
+		return nil
+	}
+
+	records = records[skip:]
+	if limit > 0 && limit < int64(len(records)) {
+		records = records[:limit]
+	}
+
+	return records
+}
@@ -0,0 +1,548 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Origho-precious/url-shortener/go/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoUrlRepository struct {
+	collection *mongo.Collection
+}
+
+type mongoVisitRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoUrlRepository) Insert(ctx context.Context, url models.Url) (
+	primitive.ObjectID, error,
+) {
+	res, err := r.collection.InsertOne(ctx, bson.M{
+		"userId":         url.UserId,
+		"deleted":        url.Deleted,
+		"deletedAt":      url.DeletedAt,
+		"createdAt":      url.CreatedAt,
+		"expiresAt":      url.ExpiresAt,
+		"visitCount":     url.VisitCount,
+		"botVisitCount":  url.BotVisitCount,
+		"customAlias":    url.CustomAlias,
+		"originalUrl":    url.OriginalUrl,
+		"shortUrlSlug":   url.ShortUrlSlug,
+		"lastVisitedAt":  url.LastVisitedAt,
+		"qrCodeImageUrl": url.QRCodeImageUrl,
+		"qrCodeFileId":   url.QRCodeFileId,
+		"passwordHash":   url.PasswordHash,
+		"maxClicks":      url.MaxClicks,
+		"activatesAt":    url.ActivatesAt,
+
+		"expiredRedirectUrl":  url.ExpiredRedirectUrl,
+		"inactiveRedirectUrl": url.InactiveRedirectUrl,
+		"fallbackUrl":         url.FallbackUrl,
+		"targetingRules":      url.TargetingRules,
+		"splitDestinations":   url.SplitDestinations,
+		"stickySplit":         url.StickySplit,
+		"utmSource":           url.UtmSource,
+		"utmMedium":           url.UtmMedium,
+		"utmCampaign":         url.UtmCampaign,
+		"utmTerm":             url.UtmTerm,
+		"utmContent":          url.UtmContent,
+		"queryPassthrough":    url.QueryPassthrough,
+		"preview":             url.Preview,
+		"ogTitle":             url.OGTitle,
+		"ogDescription":       url.OGDescription,
+		"ogImageUrl":          url.OGImageUrl,
+	})
+	if err != nil {
+		return primitive.NilObjectID, mongoError(err)
+	}
+
+	return insertedObjectID(res)
+}
+
+func (r *mongoUrlRepository) FindBySlug(ctx context.Context, slug string) (
+	models.Url, error,
+) {
+	var url models.Url
+
+	filter := bson.M{"shortUrlSlug": slug, "deleted": false}
+	err := r.collection.FindOne(ctx, filter).Decode(&url)
+	if err != nil {
+		return models.Url{}, mongoError(err)
+	}
+
+	return url, nil
+}
+
+func (r *mongoUrlRepository) FindDeletedBySlug(
+	ctx context.Context, slug string,
+) (models.Url, error) {
+	var url models.Url
+
+	filter := bson.M{"shortUrlSlug": slug, "deleted": true}
+	opts := options.FindOne().SetSort(bson.M{"deletedAt": -1})
+
+	err := r.collection.FindOne(ctx, filter, opts).Decode(&url)
+	if err != nil {
+		return models.Url{}, mongoError(err)
+	}
+
+	return url, nil
+}
+
+func (r *mongoUrlRepository) FindByID(
+	ctx context.Context, id primitive.ObjectID, userId primitive.ObjectID,
+	deleted bool,
+) (models.Url, error) {
+	var url models.Url
+
+	filter := bson.M{"_id": id, "userId": userId, "deleted": deleted}
+	err := r.collection.FindOne(ctx, filter).Decode(&url)
+	if err != nil {
+		return models.Url{}, mongoError(err)
+	}
+
+	return url, nil
+}
+
+func (r *mongoUrlRepository) Update(ctx context.Context, url models.Url) error {
+	filter := bson.M{"_id": url.ID, "userId": url.UserId, "deleted": false}
+	update := bson.M{"$set": bson.M{
+		"expiresAt":      url.ExpiresAt,
+		"customAlias":    url.CustomAlias,
+		"originalUrl":    url.OriginalUrl,
+		"shortUrlSlug":   url.ShortUrlSlug,
+		"qrCodeImageUrl": url.QRCodeImageUrl,
+		"qrCodeFileId":   url.QRCodeFileId,
+		"passwordHash":   url.PasswordHash,
+		"maxClicks":      url.MaxClicks,
+		"activatesAt":    url.ActivatesAt,
+
+		"expiredRedirectUrl":  url.ExpiredRedirectUrl,
+		"inactiveRedirectUrl": url.InactiveRedirectUrl,
+		"fallbackUrl":         url.FallbackUrl,
+		"targetingRules":      url.TargetingRules,
+		"splitDestinations":   url.SplitDestinations,
+		"stickySplit":         url.StickySplit,
+		"utmSource":           url.UtmSource,
+		"utmMedium":           url.UtmMedium,
+		"utmCampaign":         url.UtmCampaign,
+		"utmTerm":             url.UtmTerm,
+		"utmContent":          url.UtmContent,
+		"queryPassthrough":    url.QueryPassthrough,
+		"preview":             url.Preview,
+		"ogTitle":             url.OGTitle,
+		"ogDescription":       url.OGDescription,
+		"ogImageUrl":          url.OGImageUrl,
+	}}
+
+	res, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return mongoError(err)
+	}
+
+	if res.MatchedCount == 0 {
+		return models.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *mongoUrlRepository) FindActiveByOriginalUrl(
+	ctx context.Context, userId primitive.ObjectID, originalUrl string,
+	now time.Time,
+) ([]models.Url, error) {
+	filter := bson.M{
+		"userId":      userId,
+		"originalUrl": originalUrl,
+		"deleted":     false,
+		// $not also matches urls created before activation times existed.
+		"activatesAt": bson.M{"$not": bson.M{"$gt": now}},
+		"$and": bson.A{
+			bson.M{"$or": bson.A{
+				bson.M{"expiresAt": time.Time{}},
+				bson.M{"expiresAt": bson.M{"$gt": now}},
+			}},
+			mongoHasClicksLeft,
+		},
+	}
+	opts := options.Find().SetSort(bson.D{
+		{Key: "createdAt", Value: -1},
+		{Key: "_id", Value: -1},
+	})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var urls []models.Url
+	if err = cursor.All(ctx, &urls); err != nil {
+		return nil, err
+	}
+
+	return urls, nil
+}
+
+func (r *mongoUrlRepository) SetQRCode(
+	ctx context.Context, id primitive.ObjectID, qrCodeImageUrl string,
+	qrCodeFileId string,
+) error {
+	filter := bson.M{"_id": id}
+	update := bson.M{"$set": bson.M{
+		"qrCodeImageUrl": qrCodeImageUrl,
+		"qrCodeFileId":   qrCodeFileId,
+	}}
+
+	res := r.collection.FindOneAndUpdate(ctx, filter, update)
+
+	return mongoError(res.Err())
+}
+
+func (r *mongoUrlRepository) IncrementBotVisitCount(
+	ctx context.Context, id primitive.ObjectID,
+) error {
+	res := r.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": id, "deleted": false},
+		bson.M{"$inc": bson.M{"botVisitCount": 1}},
+	)
+
+	return mongoError(res.Err())
+}
+
+func (r *mongoUrlRepository) Delete(
+	ctx context.Context, id primitive.ObjectID,
+) error {
+	res := r.collection.FindOneAndDelete(ctx, bson.M{"_id": id})
+
+	return mongoError(res.Err())
+}
+
+func (r *mongoUrlRepository) FindByUser(
+	ctx context.Context, userId primitive.ObjectID, deleted bool, skip int64,
+	limit int64,
+) ([]models.Url, error) {
+	sort := bson.M{"createdAt": -1}
+	opts := options.Find().SetSort(sort).SetSkip(skip).SetLimit(limit)
+
+	filter := bson.M{"userId": userId, "deleted": deleted}
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var urls []models.Url
+	if err = cursor.All(ctx, &urls); err != nil {
+		return nil, err
+	}
+
+	return urls, nil
+}
+
+func (r *mongoUrlRepository) CountByUser(
+	ctx context.Context, userId primitive.ObjectID, deleted bool,
+) (int64, error) {
+	filter := bson.M{"userId": userId, "deleted": deleted}
+
+	return r.collection.CountDocuments(ctx, filter)
+}
+
+func (r *mongoUrlRepository) SoftDelete(
+	ctx context.Context, id primitive.ObjectID, userId primitive.ObjectID,
+	deletedAt time.Time,
+) error {
+	filter := bson.M{"_id": id, "userId": userId, "deleted": false}
+	update := bson.M{"$set": bson.M{"deleted": true, "deletedAt": deletedAt}}
+
+	res := r.collection.FindOneAndUpdate(ctx, filter, update)
+
+	return mongoError(res.Err())
+}
+
+// Restore relies on the partial unique slug index to reject a restore whose
+// slug has been taken while the url was in the trash.
+func (r *mongoUrlRepository) Restore(
+	ctx context.Context, id primitive.ObjectID, userId primitive.ObjectID,
+) error {
+	filter := bson.M{"_id": id, "userId": userId, "deleted": true}
+	update := bson.M{"$set": bson.M{"deleted": false, "deletedAt": time.Time{}}}
+
+	res := r.collection.FindOneAndUpdate(ctx, filter, update)
+
+	return mongoError(res.Err())
+}
+
+func (r *mongoUrlRepository) FindDeletedBefore(
+	ctx context.Context, cutoff time.Time, limit int64,
+) ([]models.Url, error) {
+	filter := bson.M{
+		"deleted":   true,
+		"deletedAt": bson.M{"$gt": time.Time{}, "$lt": cutoff},
+	}
+	opts := options.Find().SetSort(bson.M{"deletedAt": 1}).SetLimit(limit)
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var urls []models.Url
+	if err = cursor.All(ctx, &urls); err != nil {
+		return nil, err
+	}
+
+	return urls, nil
+}
+
+// mongoHasClicksLeft matches urls without a click limit, including those created
+// before limits existed, and urls that have not reached theirs.
+var mongoHasClicksLeft = bson.M{"$or": bson.A{
+	bson.M{"maxClicks": bson.M{"$in": bson.A{0, nil}}},
+	bson.M{"$expr": bson.M{"$lt": bson.A{"$visitCount", "$maxClicks"}}},
+}}
+
+func (r *mongoUrlRepository) IncrementVisitCount(
+	ctx context.Context, id primitive.ObjectID, visitedAt time.Time,
+) error {
+	filter := bson.M{
+		"_id":     id,
+		"deleted": false,
+		"$and":    bson.A{mongoHasClicksLeft},
+	}
+	update := bson.M{
+		"$inc": bson.M{"visitCount": 1},
+		"$set": bson.M{"lastVisitedAt": visitedAt},
+	}
+
+	res := r.collection.FindOneAndUpdate(ctx, filter, update)
+
+	return mongoError(res.Err())
+}
+
+func (r *mongoVisitRepository) Insert(ctx context.Context, visit models.Visit) (
+	primitive.ObjectID, error,
+) {
+	res, err := r.collection.InsertOne(ctx, bson.M{
+		"urlId":          visit.UrlId,
+		"browser":        visit.Browser,
+		"browserVersion": visit.BrowserVersion,
+		"os":             visit.OS,
+		"osVersion":      visit.OSVersion,
+		"vendor":         visit.Vendor,
+		"location":       visit.Location,
+		"referrer":       visit.Referrer,
+		"ipAddress":      visit.IPAddress,
+		"visitedAt":      visit.VisitedAt,
+		"deviceType":     visit.DeviceType,
+		"country":        visit.Country,
+		"variant":        visit.Variant,
+		"bot":            visit.Bot,
+		"visitorHash":    visit.VisitorHash,
+	})
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	return insertedObjectID(res)
+}
+
+// mongoVisitDimensions are the group keys of the visit dimensions.
+var mongoVisitDimensions = map[string]any{
+	models.VisitHour: bson.M{"$dateToString": bson.M{
+		"format": "%Y-%m-%dT%H:00:00Z",
+		"date":   "$visitedAt",
+	}},
+	models.VisitDay: bson.M{"$dateToString": bson.M{
+		"format": "%Y-%m-%d",
+		"date":   "$visitedAt",
+	}},
+	models.VisitUrl:      bson.M{"$toString": "$urlId"},
+	models.VisitReferrer: bson.M{"$ifNull": bson.A{"$referrer", ""}},
+	models.VisitCountry:  bson.M{"$ifNull": bson.A{"$country", ""}},
+	models.VisitDevice:   bson.M{"$ifNull": bson.A{"$deviceType", ""}},
+	models.VisitBrowser:  bson.M{"$ifNull": bson.A{"$browser", ""}},
+	models.VisitLocation: bson.M{"$ifNull": bson.A{"$location", ""}},
+}
+
+func (r *mongoVisitRepository) CountBy(
+	ctx context.Context, filter models.VisitFilter, dimension string,
+) ([]models.VisitCount, error) {
+	group, ok := mongoVisitDimensions[dimension]
+	if !ok {
+		return nil, fmt.Errorf("unknown visit dimension %q", dimension)
+	}
+
+	if len(filter.UrlIds) == 0 {
+		return nil, nil
+	}
+
+	match := bson.M{
+		"urlId":     bson.M{"$in": filter.UrlIds},
+		"visitedAt": bson.M{"$gte": filter.From, "$lt": filter.To},
+	}
+	if !filter.IncludeBots {
+		// $ne also matches visits saved before bots were flagged.
+		match["bot"] = bson.M{"$ne": true}
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{
+			"_id":      group,
+			"count":    bson.M{"$sum": 1},
+			"visitors": bson.M{"$addToSet": mongoVisitor},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"count":    1,
+			"visitors": bson.M{"$size": "$visitors"},
+		}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var groups []struct {
+		Value    string `bson:"_id"`
+		Count    int64  `bson:"count"`
+		Visitors int64  `bson:"visitors"`
+	}
+	if err = cursor.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+
+	counts := make([]models.VisitCount, 0, len(groups))
+	for _, group := range groups {
+		counts = append(counts, models.VisitCount{
+			Value:    group.Value,
+			Count:    group.Count,
+			Visitors: group.Visitors,
+		})
+	}
+
+	return counts, nil
+}
+
+// mongoVisitor identifies the visitor of a visit the way CountBy tells
+// visitors apart.
+var mongoVisitor = bson.M{"$switch": bson.M{
+	"branches": bson.A{
+		bson.M{
+			"case": bson.M{"$gt": bson.A{
+				bson.M{"$ifNull": bson.A{"$visitorHash", ""}}, "",
+			}},
+			"then": "$visitorHash",
+		},
+		bson.M{
+			"case": bson.M{"$gt": bson.A{
+				bson.M{"$ifNull": bson.A{"$ipAddress", ""}}, "",
+			}},
+			"then": "$ipAddress",
+		},
+	},
+	"default": "$_id",
+}}
+
+func (r *mongoVisitRepository) CountVisitors(
+	ctx context.Context, from time.Time, to time.Time, dimension string,
+) ([]models.VisitorCount, error) {
+	group, ok := mongoVisitDimensions[dimension]
+	if !ok {
+		return nil, fmt.Errorf("unknown visit dimension %q", dimension)
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"visitedAt": bson.M{"$gte": from, "$lt": to},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{
+				"urlId": "$urlId",
+				// Visits saved before bots were flagged are not bots.
+				"bot":   bson.M{"$eq": bson.A{"$bot", true}},
+				"value": group,
+			},
+			"clicks":   bson.M{"$sum": 1},
+			"visitors": bson.M{"$addToSet": mongoVisitor},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"clicks":   1,
+			"visitors": bson.M{"$size": "$visitors"},
+		}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var groups []struct {
+		Key struct {
+			UrlId primitive.ObjectID `bson:"urlId"`
+			Bot   bool               `bson:"bot"`
+			Value string             `bson:"value"`
+		} `bson:"_id"`
+		Clicks   int64 `bson:"clicks"`
+		Visitors int64 `bson:"visitors"`
+	}
+	if err = cursor.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+
+	counts := make([]models.VisitorCount, 0, len(groups))
+	for _, group := range groups {
+		counts = append(counts, models.VisitorCount{
+			UrlId:    group.Key.UrlId,
+			Bot:      group.Key.Bot,
+			Value:    group.Key.Value,
+			Clicks:   group.Clicks,
+			Visitors: group.Visitors,
+		})
+	}
+
+	return counts, nil
+}
+
+func (r *mongoVisitRepository) FirstVisitedAt(ctx context.Context) (
+	time.Time, error,
+) {
+	var visit models.Visit
+
+	opts := options.FindOne().SetSort(bson.M{"visitedAt": 1})
+	err := r.collection.FindOne(ctx, bson.M{}, opts).Decode(&visit)
+	if err != nil {
+		return time.Time{}, mongoError(err)
+	}
+
+	return visit.VisitedAt, nil
+}
+
+func (r *mongoVisitRepository) DeleteBefore(
+	ctx context.Context, cutoff time.Time,
+) (int64, error) {
+	res, err := r.collection.DeleteMany(
+		ctx, bson.M{"visitedAt": bson.M{"$lt": cutoff}},
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.DeletedCount, nil
+}
+
+func (r *mongoVisitRepository) DeleteByUrl(
+	ctx context.Context, urlId primitive.ObjectID,
+) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"urlId": urlId})
+
+	return err
+}
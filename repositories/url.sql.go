@@ -0,0 +1,507 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Origho-precious/url-shortener/go/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type sqlUrlRepository struct {
+	store *sqlStore
+}
+
+type sqlVisitRepository struct {
+	store *sqlStore
+}
+
+const urlColumns = `id, user_id, deleted, deleted_at, created_at, expires_at,
+	visit_count, bot_visit_count, custom_alias, original_url, short_url_slug,
+	last_visited_at, qr_code_image_url, qr_code_file_id, password_hash,
+	max_clicks, activates_at, expired_redirect_url, inactive_redirect_url,
+	fallback_url, targeting_rules, split_destinations, sticky_split,
+	utm_source, utm_medium, utm_campaign, utm_term, utm_content,
+	query_passthrough, preview, og_title, og_description, og_image_url`
+
+func scanUrl(row rowScanner) (models.Url, error) {
+	var url models.Url
+	var id, userId, targetingRules, splitDestinations string
+
+	err := row.Scan(
+		&id, &userId, &url.Deleted, &url.DeletedAt, &url.CreatedAt,
+		&url.ExpiresAt, &url.VisitCount, &url.BotVisitCount, &url.CustomAlias,
+		&url.OriginalUrl, &url.ShortUrlSlug, &url.LastVisitedAt,
+		&url.QRCodeImageUrl, &url.QRCodeFileId, &url.PasswordHash,
+		&url.MaxClicks, &url.ActivatesAt, &url.ExpiredRedirectUrl,
+		&url.InactiveRedirectUrl, &url.FallbackUrl, &targetingRules,
+		&splitDestinations, &url.StickySplit, &url.UtmSource, &url.UtmMedium,
+		&url.UtmCampaign, &url.UtmTerm, &url.UtmContent, &url.QueryPassthrough,
+		&url.Preview, &url.OGTitle, &url.OGDescription, &url.OGImageUrl,
+	)
+	if err != nil {
+		return models.Url{}, sqlError(err)
+	}
+
+	err = json.Unmarshal([]byte(targetingRules), &url.TargetingRules)
+	if err != nil {
+		return models.Url{}, err
+	}
+
+	err = json.Unmarshal([]byte(splitDestinations), &url.SplitDestinations)
+	if err != nil {
+		return models.Url{}, err
+	}
+
+	if url.ID, err = parseObjectID(id); err != nil {
+		return models.Url{}, err
+	}
+
+	if url.UserId, err = parseObjectID(userId); err != nil {
+		return models.Url{}, err
+	}
+
+	return url, nil
+}
+
+func (r *sqlUrlRepository) Insert(ctx context.Context, url models.Url) (
+	primitive.ObjectID, error,
+) {
+	id := primitive.NewObjectID()
+
+	targetingRules, err := encodeJSONArray(url.TargetingRules)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	splitDestinations, err := encodeJSONArray(url.SplitDestinations)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	_, err = r.store.exec(ctx, `INSERT INTO urls (`+urlColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?,
+			?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		id.Hex(), url.UserId.Hex(), url.Deleted, sqlTime(url.DeletedAt),
+		sqlTime(url.CreatedAt), sqlTime(url.ExpiresAt), url.VisitCount,
+		url.BotVisitCount, url.CustomAlias, url.OriginalUrl, url.ShortUrlSlug,
+		sqlTime(url.LastVisitedAt), url.QRCodeImageUrl, url.QRCodeFileId,
+		url.PasswordHash, url.MaxClicks, sqlTime(url.ActivatesAt),
+		url.ExpiredRedirectUrl, url.InactiveRedirectUrl, url.FallbackUrl,
+		targetingRules, splitDestinations, url.StickySplit, url.UtmSource,
+		url.UtmMedium, url.UtmCampaign, url.UtmTerm, url.UtmContent,
+		url.QueryPassthrough, url.Preview, url.OGTitle, url.OGDescription,
+		url.OGImageUrl,
+	)
+	if err != nil {
+		return primitive.NilObjectID, sqlError(err)
+	}
+
+	return id, nil
+}
+
+func (r *sqlUrlRepository) FindBySlug(ctx context.Context, slug string) (
+	models.Url, error,
+) {
+	row := r.store.queryRow(ctx, `SELECT `+urlColumns+` FROM urls
+		WHERE short_url_slug = ? AND deleted = ?`, slug, false)
+
+	return scanUrl(row)
+}
+
+func (r *sqlUrlRepository) FindDeletedBySlug(ctx context.Context, slug string) (
+	models.Url, error,
+) {
+	row := r.store.queryRow(ctx, `SELECT `+urlColumns+` FROM urls
+		WHERE short_url_slug = ? AND deleted = ?
+		ORDER BY deleted_at DESC, id DESC LIMIT 1`, slug, true)
+
+	return scanUrl(row)
+}
+
+func (r *sqlUrlRepository) FindByID(
+	ctx context.Context, id primitive.ObjectID, userId primitive.ObjectID,
+	deleted bool,
+) (models.Url, error) {
+	row := r.store.queryRow(ctx, `SELECT `+urlColumns+` FROM urls
+		WHERE id = ? AND user_id = ? AND deleted = ?`,
+		id.Hex(), userId.Hex(), deleted,
+	)
+
+	return scanUrl(row)
+}
+
+func (r *sqlUrlRepository) Update(ctx context.Context, url models.Url) error {
+	targetingRules, err := encodeJSONArray(url.TargetingRules)
+	if err != nil {
+		return err
+	}
+
+	splitDestinations, err := encodeJSONArray(url.SplitDestinations)
+	if err != nil {
+		return err
+	}
+
+	return r.store.execOne(ctx, `UPDATE urls SET expires_at = ?,
+		custom_alias = ?, original_url = ?, short_url_slug = ?,
+		qr_code_image_url = ?, qr_code_file_id = ?, password_hash = ?,
+		max_clicks = ?, activates_at = ?, expired_redirect_url = ?,
+		inactive_redirect_url = ?, fallback_url = ?, targeting_rules = ?,
+		split_destinations = ?, sticky_split = ?, utm_source = ?,
+		utm_medium = ?, utm_campaign = ?, utm_term = ?, utm_content = ?,
+		query_passthrough = ?, preview = ?, og_title = ?, og_description = ?,
+		og_image_url = ?
+		WHERE id = ? AND user_id = ? AND deleted = ?`,
+		sqlTime(url.ExpiresAt), url.CustomAlias, url.OriginalUrl,
+		url.ShortUrlSlug, url.QRCodeImageUrl, url.QRCodeFileId,
+		url.PasswordHash, url.MaxClicks, sqlTime(url.ActivatesAt),
+		url.ExpiredRedirectUrl, url.InactiveRedirectUrl, url.FallbackUrl,
+		targetingRules, splitDestinations, url.StickySplit, url.UtmSource,
+		url.UtmMedium, url.UtmCampaign, url.UtmTerm, url.UtmContent,
+		url.QueryPassthrough, url.Preview, url.OGTitle, url.OGDescription,
+		url.OGImageUrl, url.ID.Hex(), url.UserId.Hex(), false,
+	)
+}
+
+// encodeJSONArray stores a list as a JSON array, never as null.
+func encodeJSONArray[T any](values []T) (string, error) {
+	if values == nil {
+		values = []T{}
+	}
+
+	encoded, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+
+	return string(encoded), nil
+}
+
+func (r *sqlUrlRepository) FindActiveByOriginalUrl(
+	ctx context.Context, userId primitive.ObjectID, originalUrl string,
+	now time.Time,
+) ([]models.Url, error) {
+	rows, err := r.store.query(ctx, `SELECT `+urlColumns+` FROM urls
+		WHERE user_id = ? AND original_url = ? AND deleted = ?
+			AND (expires_at = ? OR expires_at > ?) AND activates_at <= ?
+			AND (max_clicks = 0 OR visit_count < max_clicks)
+		ORDER BY created_at DESC, id DESC`,
+		userId.Hex(), originalUrl, false, sqlTime(time.Time{}), sqlTime(now),
+		sqlTime(now),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanUrls(rows)
+}
+
+func (r *sqlUrlRepository) SetQRCode(
+	ctx context.Context, id primitive.ObjectID, qrCodeImageUrl string,
+	qrCodeFileId string,
+) error {
+	return r.store.execOne(ctx, `UPDATE urls
+		SET qr_code_image_url = ?, qr_code_file_id = ? WHERE id = ?`,
+		qrCodeImageUrl, qrCodeFileId, id.Hex(),
+	)
+}
+
+func (r *sqlUrlRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return r.store.execOne(ctx, `DELETE FROM urls WHERE id = ?`, id.Hex())
+}
+
+func (r *sqlUrlRepository) FindByUser(
+	ctx context.Context, userId primitive.ObjectID, deleted bool, skip int64,
+	limit int64,
+) ([]models.Url, error) {
+	query := `SELECT ` + urlColumns + ` FROM urls
+		WHERE user_id = ? AND deleted = ?
+		ORDER BY created_at DESC, id DESC`
+	args := []any{userId.Hex(), deleted}
+
+	if limit > 0 {
+		query += ` LIMIT ? OFFSET ?`
+		args = append(args, limit, skip)
+	}
+
+	rows, err := r.store.query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanUrls(rows)
+}
+
+func scanUrls(rows *sql.Rows) ([]models.Url, error) {
+	defer rows.Close()
+
+	var urls []models.Url
+	for rows.Next() {
+		url, err := scanUrl(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		urls = append(urls, url)
+	}
+
+	return urls, rows.Err()
+}
+
+func (r *sqlUrlRepository) CountByUser(
+	ctx context.Context, userId primitive.ObjectID, deleted bool,
+) (int64, error) {
+	var count int64
+
+	err := r.store.queryRow(ctx, `SELECT COUNT(*) FROM urls
+		WHERE user_id = ? AND deleted = ?`, userId.Hex(), deleted,
+	).Scan(&count)
+
+	return count, err
+}
+
+func (r *sqlUrlRepository) SoftDelete(
+	ctx context.Context, id primitive.ObjectID, userId primitive.ObjectID,
+	deletedAt time.Time,
+) error {
+	return r.store.execOne(ctx, `UPDATE urls SET deleted = ?, deleted_at = ?
+		WHERE id = ? AND user_id = ? AND deleted = ?`,
+		true, sqlTime(deletedAt), id.Hex(), userId.Hex(), false,
+	)
+}
+
+// Restore relies on the partial unique slug index to reject a restore whose
+// slug has been taken while the url was in the trash.
+func (r *sqlUrlRepository) Restore(
+	ctx context.Context, id primitive.ObjectID, userId primitive.ObjectID,
+) error {
+	return r.store.execOne(ctx, `UPDATE urls SET deleted = ?, deleted_at = ?
+		WHERE id = ? AND user_id = ? AND deleted = ?`,
+		false, sqlTime(time.Time{}), id.Hex(), userId.Hex(), true,
+	)
+}
+
+func (r *sqlUrlRepository) FindDeletedBefore(
+	ctx context.Context, cutoff time.Time, limit int64,
+) ([]models.Url, error) {
+	rows, err := r.store.query(ctx, `SELECT `+urlColumns+` FROM urls
+		WHERE deleted = ? AND deleted_at > ? AND deleted_at < ?
+		ORDER BY deleted_at LIMIT ?`,
+		true, sqlTime(time.Time{}), sqlTime(cutoff), limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanUrls(rows)
+}
+
+func (r *sqlUrlRepository) IncrementVisitCount(
+	ctx context.Context, id primitive.ObjectID, visitedAt time.Time,
+) error {
+	return r.store.execOne(ctx, `UPDATE urls
+		SET visit_count = visit_count + 1, last_visited_at = ?
+		WHERE id = ? AND deleted = ?
+			AND (max_clicks = 0 OR visit_count < max_clicks)`,
+		sqlTime(visitedAt), id.Hex(), false,
+	)
+}
+
+func (r *sqlUrlRepository) IncrementBotVisitCount(
+	ctx context.Context, id primitive.ObjectID,
+) error {
+	return r.store.execOne(ctx, `UPDATE urls
+		SET bot_visit_count = bot_visit_count + 1 WHERE id = ? AND deleted = ?`,
+		id.Hex(), false,
+	)
+}
+
+func (r *sqlVisitRepository) Insert(ctx context.Context, visit models.Visit) (
+	primitive.ObjectID, error,
+) {
+	id := primitive.NewObjectID()
+
+	_, err := r.store.exec(ctx, `INSERT INTO visits (`+visitColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		id.Hex(), visit.UrlId.Hex(), visit.Browser, visit.Location,
+		visit.Referrer, visit.IPAddress, sqlTime(visit.VisitedAt),
+		visit.DeviceType, visit.Country, visit.Variant, visit.Bot,
+		visit.BrowserVersion, visit.OS, visit.OSVersion, visit.Vendor,
+		visit.VisitorHash,
+	)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	return id, nil
+}
+
+const visitColumns = `id, url_id, browser, location, referrer, ip_address,
+	visited_at, device_type, country, variant, bot, browser_version, os,
+	os_version, vendor, visitor_hash`
+
+func (r *sqlVisitRepository) FirstVisitedAt(ctx context.Context) (
+	time.Time, error,
+) {
+	var visitedAt time.Time
+
+	err := r.store.queryRow(ctx, `SELECT visited_at FROM visits
+		ORDER BY visited_at LIMIT 1`,
+	).Scan(&visitedAt)
+	if err != nil {
+		return time.Time{}, sqlError(err)
+	}
+
+	return visitedAt, nil
+}
+
+func (r *sqlVisitRepository) DeleteBefore(
+	ctx context.Context, cutoff time.Time,
+) (int64, error) {
+	res, err := r.store.exec(ctx, `DELETE FROM visits WHERE visited_at < ?`,
+		sqlTime(cutoff),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// visitDimension returns the expression grouping visits by dimension.
+func (r *sqlVisitRepository) visitDimension(dimension string) (string, error) {
+	switch dimension {
+	case models.VisitHour:
+		if r.store.dialect == "postgres" {
+			return `to_char(visited_at AT TIME ZONE 'UTC',
+				'YYYY-MM-DD"T"HH24":00:00Z"')`, nil
+		}
+
+		return `strftime('%Y-%m-%dT%H:00:00Z', visited_at)`, nil
+	case models.VisitDay:
+		if r.store.dialect == "postgres" {
+			return `to_char(visited_at AT TIME ZONE 'UTC', 'YYYY-MM-DD')`, nil
+		}
+
+		return `strftime('%Y-%m-%d', visited_at)`, nil
+	case models.VisitUrl:
+		return "url_id", nil
+	case models.VisitReferrer:
+		return "referrer", nil
+	case models.VisitCountry:
+		return "country", nil
+	case models.VisitDevice:
+		return "device_type", nil
+	case models.VisitBrowser:
+		return "browser", nil
+	case models.VisitLocation:
+		return "location", nil
+	}
+
+	return "", fmt.Errorf("unknown visit dimension %q", dimension)
+}
+
+// sqlVisitor identifies the visitor of a visit the way CountBy tells
+// visitors apart.
+const sqlVisitor = `COALESCE(NULLIF(visitor_hash, ''), NULLIF(ip_address, ''), id)`
+
+func (r *sqlVisitRepository) CountBy(
+	ctx context.Context, filter models.VisitFilter, dimension string,
+) ([]models.VisitCount, error) {
+	group, err := r.visitDimension(dimension)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(filter.UrlIds) == 0 {
+		return nil, nil
+	}
+
+	placeholders := strings.Repeat("?, ", len(filter.UrlIds)-1) + "?"
+	args := []any{sqlTime(filter.From), sqlTime(filter.To)}
+	for _, urlId := range filter.UrlIds {
+		args = append(args, urlId.Hex())
+	}
+
+	query := `SELECT ` + group + `, COUNT(*), COUNT(DISTINCT ` + sqlVisitor + `)
+		FROM visits WHERE visited_at >= ? AND visited_at < ?
+			AND url_id IN (` + placeholders + `)`
+	if !filter.IncludeBots {
+		query += ` AND bot = ?`
+		args = append(args, false)
+	}
+	query += ` GROUP BY 1`
+
+	rows, err := r.store.query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []models.VisitCount
+	for rows.Next() {
+		var count models.VisitCount
+
+		err = rows.Scan(&count.Value, &count.Count, &count.Visitors)
+		if err != nil {
+			return nil, err
+		}
+
+		counts = append(counts, count)
+	}
+
+	return counts, rows.Err()
+}
+
+func (r *sqlVisitRepository) CountVisitors(
+	ctx context.Context, from time.Time, to time.Time, dimension string,
+) ([]models.VisitorCount, error) {
+	group, err := r.visitDimension(dimension)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.store.query(ctx, `SELECT url_id, bot, `+group+`, COUNT(*),
+			COUNT(DISTINCT `+sqlVisitor+`)
+		FROM visits WHERE visited_at >= ? AND visited_at < ?
+		GROUP BY 1, 2, 3`, sqlTime(from), sqlTime(to),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []models.VisitorCount
+	for rows.Next() {
+		var count models.VisitorCount
+		var urlId string
+
+		err = rows.Scan(
+			&urlId, &count.Bot, &count.Value, &count.Clicks, &count.Visitors,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if count.UrlId, err = parseObjectID(urlId); err != nil {
+			return nil, err
+		}
+
+		counts = append(counts, count)
+	}
+
+	return counts, rows.Err()
+}
+
+func (r *sqlVisitRepository) DeleteByUrl(
+	ctx context.Context, urlId primitive.ObjectID,
+) error {
+	_, err := r.store.exec(ctx, `DELETE FROM visits WHERE url_id = ?`, urlId.Hex())
+
+	return err
+}
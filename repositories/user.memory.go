@@ -0,0 +1,245 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Origho-precious/url-shortener/go/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryUserRepository struct {
+	mu    sync.RWMutex
+	users map[primitive.ObjectID]models.User
+}
+
+type memoryForgotPasswordRepository struct {
+	mu      sync.RWMutex
+	records map[primitive.ObjectID]models.ForgotPassword
+}
+
+type memoryVerificationTokenRepository struct {
+	mu      sync.RWMutex
+	records map[primitive.ObjectID]models.VerificationToken
+}
+
+func (r *memoryUserRepository) Insert(_ context.Context, user models.User) (
+	primitive.ObjectID, error,
+) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, taken := r.findByEmail(user.Email); taken {
+		return primitive.NilObjectID, models.ErrDuplicateKey
+	}
+
+	user.ID = primitive.NewObjectID()
+	r.users[user.ID] = user
+
+	return user.ID, nil
+}
+
+func (r *memoryUserRepository) FindByID(
+	_ context.Context, id primitive.ObjectID,
+) (models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return models.User{}, models.ErrNotFound
+	}
+
+	return user, nil
+}
+
+func (r *memoryUserRepository) findByEmail(email string) (models.User, bool) {
+	for _, user := range r.users {
+		if user.Email == email {
+			return user, true
+		}
+	}
+
+	return models.User{}, false
+}
+
+func (r *memoryUserRepository) FindByEmail(_ context.Context, email string) (
+	models.User, error,
+) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.findByEmail(email)
+	if !ok {
+		return models.User{}, models.ErrNotFound
+	}
+
+	return user, nil
+}
+
+func (r *memoryUserRepository) SetEmailVerified(
+	_ context.Context, id primitive.ObjectID,
+) (models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return models.User{}, models.ErrNotFound
+	}
+
+	user.EmailVerified = true
+	r.users[id] = user
+
+	return user, nil
+}
+
+func (r *memoryUserRepository) UpdatePassword(
+	_ context.Context, email string, passwordHash string,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.findByEmail(email)
+	if !ok {
+		return models.ErrNotFound
+	}
+
+	user.Password = passwordHash
+	user.EmailVerified = true
+	r.users[user.ID] = user
+
+	return nil
+}
+
+func (r *memoryUserRepository) UpdateFullName(
+	_ context.Context, id primitive.ObjectID, fullName string,
+) (models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return models.User{}, models.ErrNotFound
+	}
+
+	user.FullName = fullName
+	r.users[id] = user
+
+	return user, nil
+}
+
+func (r *memoryUserRepository) UpdateFallbackUrl(
+	_ context.Context, id primitive.ObjectID, fallbackUrl string,
+) (models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return models.User{}, models.ErrNotFound
+	}
+
+	user.FallbackUrl = fallbackUrl
+	r.users[id] = user
+
+	return user, nil
+}
+
+func (r *memoryForgotPasswordRepository) Insert(
+	_ context.Context, record models.ForgotPassword,
+) (primitive.ObjectID, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	record.ID = primitive.NewObjectID()
+	r.records[record.ID] = record
+
+	return record.ID, nil
+}
+
+func (r *memoryForgotPasswordRepository) FindByID(
+	_ context.Context, id primitive.ObjectID,
+) (models.ForgotPassword, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	record, ok := r.records[id]
+	if !ok {
+		return models.ForgotPassword{}, models.ErrNotFound
+	}
+
+	return record, nil
+}
+
+func (r *memoryForgotPasswordRepository) Delete(
+	_ context.Context, id primitive.ObjectID,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.records[id]; !ok {
+		return models.ErrNotFound
+	}
+
+	delete(r.records, id)
+
+	return nil
+}
+
+func (r *memoryVerificationTokenRepository) Insert(
+	_ context.Context, record models.VerificationToken,
+) (primitive.ObjectID, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	record.ID = primitive.NewObjectID()
+	r.records[record.ID] = record
+
+	return record.ID, nil
+}
+
+func (r *memoryVerificationTokenRepository) FindByUser(
+	_ context.Context, userId primitive.ObjectID,
+) (models.VerificationToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, record := range r.records {
+		if record.UserId == userId {
+			return record, nil
+		}
+	}
+
+	return models.VerificationToken{}, models.ErrNotFound
+}
+
+func (r *memoryVerificationTokenRepository) Find(
+	_ context.Context, userId primitive.ObjectID, token string,
+) (models.VerificationToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, record := range r.records {
+		if record.UserId == userId && record.Token == token {
+			return record, nil
+		}
+	}
+
+	return models.VerificationToken{}, models.ErrNotFound
+}
+
+func (r *memoryVerificationTokenRepository) Delete(
+	_ context.Context, id primitive.ObjectID,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.records[id]; !ok {
+		return models.ErrNotFound
+	}
+
+	delete(r.records, id)
+
+	return nil
+}
@@ -0,0 +1,203 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/Origho-precious/url-shortener/go/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoUserRepository struct {
+	collection *mongo.Collection
+}
+
+type mongoForgotPasswordRepository struct {
+	collection *mongo.Collection
+}
+
+type mongoVerificationTokenRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoUserRepository) Insert(ctx context.Context, user models.User) (
+	primitive.ObjectID, error,
+) {
+	res, err := r.collection.InsertOne(ctx, bson.M{
+		"email":         user.Email,
+		"fullName":      user.FullName,
+		"password":      user.Password,
+		"createdAt":     user.CreatedAt,
+		"emailVerified": user.EmailVerified,
+		"fallbackUrl":   user.FallbackUrl,
+	})
+	if err != nil {
+		return primitive.NilObjectID, mongoError(err)
+	}
+
+	return insertedObjectID(res)
+}
+
+func (r *mongoUserRepository) findOne(ctx context.Context, filter bson.M) (
+	models.User, error,
+) {
+	var user models.User
+
+	err := r.collection.FindOne(ctx, filter).Decode(&user)
+	if err != nil {
+		return models.User{}, mongoError(err)
+	}
+
+	return user, nil
+}
+
+func (r *mongoUserRepository) updateOne(
+	ctx context.Context, filter bson.M, update bson.M,
+) (models.User, error) {
+	var user models.User
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&user)
+	if err != nil {
+		return models.User{}, mongoError(err)
+	}
+
+	return user, nil
+}
+
+func (r *mongoUserRepository) FindByID(
+	ctx context.Context, id primitive.ObjectID,
+) (models.User, error) {
+	return r.findOne(ctx, bson.M{"_id": id})
+}
+
+func (r *mongoUserRepository) FindByEmail(ctx context.Context, email string) (
+	models.User, error,
+) {
+	return r.findOne(ctx, bson.M{"email": email})
+}
+
+func (r *mongoUserRepository) SetEmailVerified(
+	ctx context.Context, id primitive.ObjectID,
+) (models.User, error) {
+	filter := bson.M{"_id": id}
+	update := bson.M{"$set": bson.M{"emailVerified": true}}
+
+	return r.updateOne(ctx, filter, update)
+}
+
+func (r *mongoUserRepository) UpdatePassword(
+	ctx context.Context, email string, passwordHash string,
+) error {
+	filter := bson.M{"email": email}
+	update := bson.M{"$set": bson.M{
+		"password": passwordHash, "emailVerified": true,
+	}}
+
+	_, err := r.updateOne(ctx, filter, update)
+
+	return err
+}
+
+func (r *mongoUserRepository) UpdateFullName(
+	ctx context.Context, id primitive.ObjectID, fullName string,
+) (models.User, error) {
+	filter := bson.M{"_id": id}
+	update := bson.M{"$set": bson.M{"fullName": fullName}}
+
+	return r.updateOne(ctx, filter, update)
+}
+
+func (r *mongoUserRepository) UpdateFallbackUrl(
+	ctx context.Context, id primitive.ObjectID, fallbackUrl string,
+) (models.User, error) {
+	filter := bson.M{"_id": id}
+	update := bson.M{"$set": bson.M{"fallbackUrl": fallbackUrl}}
+
+	return r.updateOne(ctx, filter, update)
+}
+
+func (r *mongoForgotPasswordRepository) Insert(
+	ctx context.Context, record models.ForgotPassword,
+) (primitive.ObjectID, error) {
+	res, err := r.collection.InsertOne(ctx, bson.M{
+		"userEmail": record.UserEmail,
+		"createdAt": record.CreatedAt,
+	})
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	return insertedObjectID(res)
+}
+
+func (r *mongoForgotPasswordRepository) FindByID(
+	ctx context.Context, id primitive.ObjectID,
+) (models.ForgotPassword, error) {
+	var record models.ForgotPassword
+
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&record)
+	if err != nil {
+		return models.ForgotPassword{}, mongoError(err)
+	}
+
+	return record, nil
+}
+
+func (r *mongoForgotPasswordRepository) Delete(
+	ctx context.Context, id primitive.ObjectID,
+) error {
+	res := r.collection.FindOneAndDelete(ctx, bson.M{"_id": id})
+
+	return mongoError(res.Err())
+}
+
+func (r *mongoVerificationTokenRepository) Insert(
+	ctx context.Context, record models.VerificationToken,
+) (primitive.ObjectID, error) {
+	res, err := r.collection.InsertOne(ctx, bson.M{
+		"token":     record.Token,
+		"userId":    record.UserId,
+		"createdAt": record.CreatedAt,
+	})
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	return insertedObjectID(res)
+}
+
+func (r *mongoVerificationTokenRepository) findOne(
+	ctx context.Context, filter bson.M,
+) (models.VerificationToken, error) {
+	var record models.VerificationToken
+
+	err := r.collection.FindOne(ctx, filter).Decode(&record)
+	if err != nil {
+		return models.VerificationToken{}, mongoError(err)
+	}
+
+	return record, nil
+}
+
+func (r *mongoVerificationTokenRepository) FindByUser(
+	ctx context.Context, userId primitive.ObjectID,
+) (models.VerificationToken, error) {
+	return r.findOne(ctx, bson.M{"userId": userId})
+}
+
+func (r *mongoVerificationTokenRepository) Find(
+	ctx context.Context, userId primitive.ObjectID, token string,
+) (models.VerificationToken, error) {
+	return r.findOne(ctx, bson.M{"userId": userId, "token": token})
+}
+
+func (r *mongoVerificationTokenRepository) Delete(
+	ctx context.Context, id primitive.ObjectID,
+) error {
+	res := r.collection.FindOneAndDelete(ctx, bson.M{"_id": id})
+
+	return mongoError(res.Err())
+}
@@ -0,0 +1,233 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/Origho-precious/url-shortener/go/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type sqlUserRepository struct {
+	store *sqlStore
+}
+
+type sqlForgotPasswordRepository struct {
+	store *sqlStore
+}
+
+type sqlVerificationTokenRepository struct {
+	store *sqlStore
+}
+
+const userColumns = `id, email, full_name, password, created_at, email_verified,
+	fallback_url`
+
+func scanUser(row rowScanner) (models.User, error) {
+	var user models.User
+	var id string
+
+	err := row.Scan(
+		&id, &user.Email, &user.FullName, &user.Password, &user.CreatedAt,
+		&user.EmailVerified, &user.FallbackUrl,
+	)
+	if err != nil {
+		return models.User{}, sqlError(err)
+	}
+
+	if user.ID, err = parseObjectID(id); err != nil {
+		return models.User{}, err
+	}
+
+	return user, nil
+}
+
+func (r *sqlUserRepository) Insert(ctx context.Context, user models.User) (
+	primitive.ObjectID, error,
+) {
+	id := primitive.NewObjectID()
+
+	_, err := r.store.exec(ctx, `INSERT INTO users (`+userColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		id.Hex(), user.Email, user.FullName, user.Password,
+		sqlTime(user.CreatedAt), user.EmailVerified, user.FallbackUrl,
+	)
+	if err != nil {
+		return primitive.NilObjectID, sqlError(err)
+	}
+
+	return id, nil
+}
+
+func (r *sqlUserRepository) FindByID(
+	ctx context.Context, id primitive.ObjectID,
+) (models.User, error) {
+	row := r.store.queryRow(
+		ctx, `SELECT `+userColumns+` FROM users WHERE id = ?`, id.Hex(),
+	)
+
+	return scanUser(row)
+}
+
+func (r *sqlUserRepository) FindByEmail(ctx context.Context, email string) (
+	models.User, error,
+) {
+	row := r.store.queryRow(
+		ctx, `SELECT `+userColumns+` FROM users WHERE email = ?`, email,
+	)
+
+	return scanUser(row)
+}
+
+func (r *sqlUserRepository) SetEmailVerified(
+	ctx context.Context, id primitive.ObjectID,
+) (models.User, error) {
+	err := r.store.execOne(
+		ctx, `UPDATE users SET email_verified = ? WHERE id = ?`, true, id.Hex(),
+	)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	return r.FindByID(ctx, id)
+}
+
+func (r *sqlUserRepository) UpdatePassword(
+	ctx context.Context, email string, passwordHash string,
+) error {
+	return r.store.execOne(ctx, `UPDATE users
+		SET password = ?, email_verified = ? WHERE email = ?`,
+		passwordHash, true, email,
+	)
+}
+
+func (r *sqlUserRepository) UpdateFullName(
+	ctx context.Context, id primitive.ObjectID, fullName string,
+) (models.User, error) {
+	err := r.store.execOne(
+		ctx, `UPDATE users SET full_name = ? WHERE id = ?`, fullName, id.Hex(),
+	)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	return r.FindByID(ctx, id)
+}
+
+func (r *sqlUserRepository) UpdateFallbackUrl(
+	ctx context.Context, id primitive.ObjectID, fallbackUrl string,
+) (models.User, error) {
+	err := r.store.execOne(
+		ctx, `UPDATE users SET fallback_url = ? WHERE id = ?`,
+		fallbackUrl, id.Hex(),
+	)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	return r.FindByID(ctx, id)
+}
+
+func (r *sqlForgotPasswordRepository) Insert(
+	ctx context.Context, record models.ForgotPassword,
+) (primitive.ObjectID, error) {
+	id := primitive.NewObjectID()
+
+	_, err := r.store.exec(ctx, `INSERT INTO forgot_passwords
+		(id, user_email, created_at) VALUES (?, ?, ?)`,
+		id.Hex(), record.UserEmail, sqlTime(record.CreatedAt),
+	)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	return id, nil
+}
+
+func (r *sqlForgotPasswordRepository) FindByID(
+	ctx context.Context, id primitive.ObjectID,
+) (models.ForgotPassword, error) {
+	record := models.ForgotPassword{ID: id}
+
+	err := r.store.queryRow(ctx, `SELECT user_email, created_at
+		FROM forgot_passwords WHERE id = ?`, id.Hex(),
+	).Scan(&record.UserEmail, &record.CreatedAt)
+	if err != nil {
+		return models.ForgotPassword{}, sqlError(err)
+	}
+
+	return record, nil
+}
+
+func (r *sqlForgotPasswordRepository) Delete(
+	ctx context.Context, id primitive.ObjectID,
+) error {
+	return r.store.execOne(
+		ctx, `DELETE FROM forgot_passwords WHERE id = ?`, id.Hex(),
+	)
+}
+
+func scanVerificationToken(row rowScanner) (models.VerificationToken, error) {
+	var record models.VerificationToken
+	var id, userId string
+
+	err := row.Scan(&id, &record.Token, &userId, &record.CreatedAt)
+	if err != nil {
+		return models.VerificationToken{}, sqlError(err)
+	}
+
+	if record.ID, err = parseObjectID(id); err != nil {
+		return models.VerificationToken{}, err
+	}
+
+	if record.UserId, err = parseObjectID(userId); err != nil {
+		return models.VerificationToken{}, err
+	}
+
+	return record, nil
+}
+
+func (r *sqlVerificationTokenRepository) Insert(
+	ctx context.Context, record models.VerificationToken,
+) (primitive.ObjectID, error) {
+	id := primitive.NewObjectID()
+
+	_, err := r.store.exec(ctx, `INSERT INTO verification_tokens
+		(id, token, user_id, created_at) VALUES (?, ?, ?, ?)`,
+		id.Hex(), record.Token, record.UserId.Hex(), sqlTime(record.CreatedAt),
+	)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	return id, nil
+}
+
+func (r *sqlVerificationTokenRepository) FindByUser(
+	ctx context.Context, userId primitive.ObjectID,
+) (models.VerificationToken, error) {
+	row := r.store.queryRow(ctx, `SELECT id, token, user_id, created_at
+		FROM verification_tokens WHERE user_id = ?
+		ORDER BY created_at LIMIT 1`, userId.Hex(),
+	)
+
+	return scanVerificationToken(row)
+}
+
+func (r *sqlVerificationTokenRepository) Find(
+	ctx context.Context, userId primitive.ObjectID, token string,
+) (models.VerificationToken, error) {
+	row := r.store.queryRow(ctx, `SELECT id, token, user_id, created_at
+		FROM verification_tokens WHERE user_id = ? AND token = ?`,
+		userId.Hex(), token,
+	)
+
+	return scanVerificationToken(row)
+}
+
+func (r *sqlVerificationTokenRepository) Delete(
+	ctx context.Context, id primitive.ObjectID,
+) error {
+	return r.store.execOne(
+		ctx, `DELETE FROM verification_tokens WHERE id = ?`, id.Hex(),
+	)
+}
@@ -0,0 +1,43 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Origho-precious/url-shortener/go/models"
+)
+
+type memoryVisitorSaltRepository struct {
+	mu    sync.Mutex
+	salts map[string]models.VisitorSalt
+}
+
+func (r *memoryVisitorSaltRepository) FindOrInsert(
+	_ context.Context, salt models.VisitorSalt,
+) (models.VisitorSalt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.salts[salt.Day]; ok {
+		return existing, nil
+	}
+
+	r.salts[salt.Day] = salt
+
+	return salt, nil
+}
+
+func (r *memoryVisitorSaltRepository) DeleteBefore(
+	_ context.Context, day string,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for saltDay := range r.salts {
+		if saltDay < day {
+			delete(r.salts, saltDay)
+		}
+	}
+
+	return nil
+}
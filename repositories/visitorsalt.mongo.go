@@ -0,0 +1,43 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/Origho-precious/url-shortener/go/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoVisitorSaltRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoVisitorSaltRepository) FindOrInsert(
+	ctx context.Context, salt models.VisitorSalt,
+) (models.VisitorSalt, error) {
+	var stored models.VisitorSalt
+
+	filter := bson.M{"_id": salt.Day}
+	update := bson.M{"$setOnInsert": bson.M{"salt": salt.Salt}}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(
+		options.After,
+	)
+
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(
+		&stored,
+	)
+	if err != nil {
+		return models.VisitorSalt{}, mongoError(err)
+	}
+
+	return stored, nil
+}
+
+func (r *mongoVisitorSaltRepository) DeleteBefore(
+	ctx context.Context, day string,
+) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$lt": day}})
+
+	return err
+}
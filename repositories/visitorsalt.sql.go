@@ -0,0 +1,41 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/Origho-precious/url-shortener/go/models"
+)
+
+type sqlVisitorSaltRepository struct {
+	store *sqlStore
+}
+
+func (r *sqlVisitorSaltRepository) FindOrInsert(
+	ctx context.Context, salt models.VisitorSalt,
+) (models.VisitorSalt, error) {
+	_, err := r.store.exec(ctx, `INSERT INTO visitor_salts (day, salt)
+		VALUES (?, ?) ON CONFLICT (day) DO NOTHING`, salt.Day, salt.Salt,
+	)
+	if err != nil {
+		return models.VisitorSalt{}, sqlError(err)
+	}
+
+	var stored models.VisitorSalt
+
+	err = r.store.queryRow(ctx, `SELECT day, salt FROM visitor_salts
+		WHERE day = ?`, salt.Day,
+	).Scan(&stored.Day, &stored.Salt)
+	if err != nil {
+		return models.VisitorSalt{}, sqlError(err)
+	}
+
+	return stored, nil
+}
+
+func (r *sqlVisitorSaltRepository) DeleteBefore(
+	ctx context.Context, day string,
+) error {
+	_, err := r.store.exec(ctx, `DELETE FROM visitor_salts WHERE day < ?`, day)
+
+	return err
+}
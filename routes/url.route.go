@@ -4,28 +4,50 @@ import (
 	"github.com/Origho-precious/url-shortener/go/controllers"
 	"github.com/Origho-precious/url-shortener/go/middlewares"
 	"github.com/Origho-precious/url-shortener/go/models"
+	"github.com/Origho-precious/url-shortener/go/services"
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func UrlRouter(r *gin.Engine, DB *mongo.Database) {
-	urlCollection := DB.Collection("Urls")
-	userCollection := DB.Collection("Users")
-	visitCollection := DB.Collection("Visits")
-
+func UrlRouter(
+	r *gin.Engine, repos *models.Repositories, geoIP *services.GeoIP,
+) {
 	urlService := &models.UrlService{
-		UrlCollection:   urlCollection,
-		VisitCollection: visitCollection,
+		UrlRepository:      repos.Urls,
+		VisitRepository:    repos.Visits,
+		CounterRepository:  repos.Counters,
+		RevisionRepository: repos.UrlRevisions,
+		UserRepository:     repos.Users,
+		RollupRepository:   repos.VisitRollups,
+		GeoIP:              geoIP,
+		VisitorHasher:      models.NewVisitorHasher(repos.VisitorSalts),
 	}
 
 	userService := &models.UserService{
-		UserCollection: userCollection,
+		UserRepository: repos.Users,
 	}
 
 	validateAuthToken := middlewares.ValidateAuthToken
 
-	r.GET("/redirect/:slug", func(c *gin.Context) {
+	redirect := func(c *gin.Context) {
 		controllers.RedirectToLongUrl(c, urlService)
+	}
+	r.GET("/redirect/:slug", redirect)
+	// HEAD requests come from link checkers and monitors, they are recorded
+	// as bot visits.
+	r.HEAD("/redirect/:slug", redirect)
+
+	r.POST("/redirect/:slug", func(c *gin.Context) {
+		controllers.UnlockProtectedUrl(c, urlService)
+	})
+
+	continueRedirect := func(c *gin.Context) {
+		controllers.ContinueToLongUrl(c, urlService)
+	}
+	r.GET("/redirect/:slug/continue", continueRedirect)
+	r.HEAD("/redirect/:slug/continue", continueRedirect)
+
+	r.POST("/redirect/:slug/continue", func(c *gin.Context) {
+		controllers.UnlockProtectedUrl(c, urlService)
 	})
 
 	router := r.Group("/v1/api/urls")
@@ -40,9 +62,39 @@ func UrlRouter(r *gin.Engine, DB *mongo.Database) {
 			controllers.GetUrlsByUserID(c, urlService)
 		})
 
+		router.GET("/trash", validateAuthToken(), func(c *gin.Context) {
+			controllers.GetTrashedUrls(c, urlService)
+		})
+
+		router.PATCH("/:id", validateAuthToken(), func(c *gin.Context) {
+			controllers.HandleUrlUpdate(c, urlService, userService)
+		})
+
+		router.GET("/:id/history", validateAuthToken(), func(c *gin.Context) {
+			controllers.GetUrlHistory(c, urlService)
+		})
+
+		router.GET("/:id/analytics", validateAuthToken(), func(c *gin.Context) {
+			controllers.GetUrlAnalytics(c, urlService)
+		})
+
+		router.POST("/:id/history/:revisionId/rollback", validateAuthToken(),
+			func(c *gin.Context) {
+				controllers.HandleUrlRollback(c, urlService, userService)
+			},
+		)
+
 		router.DELETE("/:id/delete", validateAuthToken(), func(c *gin.Context) {
 			controllers.HandleUrlDelete(c, urlService, userService)
 		})
+
+		router.POST("/:id/restore", validateAuthToken(), func(c *gin.Context) {
+			controllers.HandleUrlRestore(c, urlService, userService)
+		})
+
+		router.DELETE("/:id/purge", validateAuthToken(), func(c *gin.Context) {
+			controllers.HandleUrlPurge(c, urlService, userService)
+		})
 	}
 
 }
@@ -0,0 +1,125 @@
+package routes_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Origho-precious/url-shortener/go/models"
+	"github.com/Origho-precious/url-shortener/go/repositories"
+	"github.com/Origho-precious/url-shortener/go/routes"
+	"github.com/Origho-precious/url-shortener/go/templates"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const browserUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) " +
+	"AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36"
+
+// TestRedirectHeadRequests checks that HEAD requests to short urls are
+// counted as bot visits, even from a browser user agent, and never use up
+// the clicks of click limited urls.
+func TestRedirectHeadRequests(t *testing.T) {
+	t.Setenv("GIN_MODE", "test")
+	gin.SetMode(gin.TestMode)
+
+	pages, err := templates.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repos := repositories.NewMemoryRepositories()
+	r := gin.New()
+	r.SetHTMLTemplate(pages)
+	routes.UrlRouter(r, repos, nil)
+
+	insert := func(slug string, maxClicks int64) {
+		t.Helper()
+
+		_, err := repos.Urls.Insert(context.Background(), models.Url{
+			UserId:       primitive.NewObjectID(),
+			OriginalUrl:  "https://example.com/" + slug,
+			ShortUrlSlug: slug,
+			MaxClicks:    maxClicks,
+			CreatedAt:    time.Now(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	request := func(method string, path string) *httptest.ResponseRecorder {
+		t.Helper()
+
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("User-Agent", browserUserAgent)
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		return w
+	}
+
+	counts := func(slug string) (int64, int64) {
+		t.Helper()
+
+		url, err := repos.Urls.FindBySlug(context.Background(), slug)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return url.VisitCount, url.BotVisitCount
+	}
+
+	t.Run("url without a click limit", func(t *testing.T) {
+		insert("open", 0)
+
+		w := request(http.MethodHead, "/redirect/open")
+		if w.Code != http.StatusTemporaryRedirect {
+			t.Fatalf("HEAD answered %d, want %d", w.Code, http.StatusTemporaryRedirect)
+		}
+
+		if location := w.Header().Get("Location"); location != "https://example.com/open" {
+			t.Errorf("HEAD redirected to %q", location)
+		}
+
+		if visits, botVisits := counts("open"); visits != 0 || botVisits != 1 {
+			t.Errorf("got %d visits and %d bot visits, want 0 and 1", visits, botVisits)
+		}
+	})
+
+	t.Run("one-time url", func(t *testing.T) {
+		insert("once", 1)
+
+		for _, path := range []string{"/redirect/once", "/redirect/once/continue"} {
+			w := request(http.MethodHead, path)
+			if w.Code != http.StatusNoContent {
+				t.Fatalf("HEAD %s answered %d, want %d", path, w.Code, http.StatusNoContent)
+			}
+
+			if location := w.Header().Get("Location"); location != "" {
+				t.Errorf("HEAD %s redirected to %q", path, location)
+			}
+		}
+
+		if visits, botVisits := counts("once"); visits != 0 || botVisits != 2 {
+			t.Errorf("got %d visits and %d bot visits, want 0 and 2", visits, botVisits)
+		}
+
+		w := request(http.MethodGet, "/redirect/once")
+		if w.Code != http.StatusTemporaryRedirect {
+			t.Fatalf("GET after HEAD answered %d, want %d", w.Code, http.StatusTemporaryRedirect)
+		}
+
+		if visits, _ := counts("once"); visits != 1 {
+			t.Errorf("got %d visits after GET, want 1", visits)
+		}
+
+		w = request(http.MethodGet, "/redirect/once")
+		if w.Code == http.StatusTemporaryRedirect {
+			t.Errorf("second GET was redirected past the click limit")
+		}
+	})
+}
@@ -5,18 +5,19 @@ import (
 	"github.com/Origho-precious/url-shortener/go/middlewares"
 	"github.com/Origho-precious/url-shortener/go/models"
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func UserRouter(r *gin.Engine, DB *mongo.Database) {
-	userCollection := DB.Collection("Users")
-	forgotPasswordCollection := DB.Collection("ForgotPassword")
-	verificationTokenCollection := DB.Collection("VerificationToken")
-
+func UserRouter(r *gin.Engine, repos *models.Repositories) {
 	userService := &models.UserService{
-		UserCollection:              userCollection,
-		ForgotPasswordCollection:    forgotPasswordCollection,
-		VerificationTokenCollection: verificationTokenCollection,
+		UserRepository:              repos.Users,
+		ForgotPasswordRepository:    repos.ForgotPasswords,
+		VerificationTokenRepository: repos.VerificationTokens,
+	}
+
+	urlService := &models.UrlService{
+		UrlRepository:    repos.Urls,
+		VisitRepository:  repos.Visits,
+		RollupRepository: repos.VisitRollups,
 	}
 
 	validateAuthToken := middlewares.ValidateAuthToken
@@ -54,7 +55,7 @@ func UserRouter(r *gin.Engine, DB *mongo.Database) {
 
 		// Route for resetting password
 		usersRouter.PATCH("/reset-password",
-			middlewares.ValidateResetPasswordToken(forgotPasswordCollection),
+			middlewares.ValidateResetPasswordToken(repos.ForgotPasswords),
 			func(c *gin.Context) {
 				controllers.HandlePasswordReset(c, userService)
 			},
@@ -65,9 +66,23 @@ func UserRouter(r *gin.Engine, DB *mongo.Database) {
 			controllers.GetUserProfile(c, userService)
 		})
 
+		// Route for the analytics of all the user's links
+		usersRouter.GET("/me/analytics", validateAuthToken(),
+			func(c *gin.Context) {
+				controllers.GetAccountAnalytics(c, urlService)
+			},
+		)
+
 		// Route for editing full name
 		usersRouter.PATCH("/edit", validateAuthToken(), func(c *gin.Context) {
 			controllers.HandleUserFullNameEdit(c, userService)
 		})
+
+		// Route for setting the account's fallback url
+		usersRouter.PATCH("/fallback-url", validateAuthToken(),
+			func(c *gin.Context) {
+				controllers.HandleUserFallbackUrlEdit(c, userService)
+			},
+		)
 	}
 }
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/Origho-precious/url-shortener/go/configs"
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// GeoLocation is where an ip address was found to be. Fields the database
+// has no answer for are left empty.
+type GeoLocation struct {
+	// Country is an ISO 3166-1 alpha-2 code such as "NG".
+	Country     string
+	CountryName string
+	Region      string
+	City        string
+}
+
+// String joins the known parts of l from the most to the least precise, e.g.
+// "Lagos, Lagos, Nigeria".
+func (l GeoLocation) String() string {
+	var parts []string
+	for _, part := range []string{l.City, l.Region, l.CountryName} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+
+	if len(parts) == 0 {
+		return l.Country
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// GeoIP resolves ip addresses with a local MaxMind-format (mmdb) database,
+// such as GeoLite2 City or Country. A nil *GeoIP resolves nothing.
+type GeoIP struct {
+	reader *maxminddb.Reader
+}
+
+type geoIPRecord struct {
+	Country struct {
+		ISOCode string            `maxminddb:"iso_code"`
+		Names   map[string]string `maxminddb:"names"`
+	} `maxminddb:"country"`
+	Subdivisions []struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"subdivisions"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+}
+
+// NewGeoIP opens the database at GEOIP_DB_PATH. It returns nil when the
+// setting is empty, leaving visits without a location.
+func NewGeoIP() (*GeoIP, error) {
+	cfg, err := configs.LoadEnvs()
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.GEOIP_DB_PATH == "" {
+		return nil, nil
+	}
+
+	return OpenGeoIP(cfg.GEOIP_DB_PATH)
+}
+
+// OpenGeoIP opens the mmdb file at path.
+func OpenGeoIP(path string) (*GeoIP, error) {
+	reader, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening geoip database: %w", err)
+	}
+
+	return &GeoIP{reader: reader}, nil
+}
+
+// Lookup resolves ip. Addresses missing from the database, such as private
+// ones, resolve to an empty GeoLocation.
+func (g *GeoIP) Lookup(ip string) (GeoLocation, error) {
+	if g == nil {
+		return GeoLocation{}, nil
+	}
+
+	parsedIP := net.ParseIP(ip)
+	if parsedIP == nil {
+		return GeoLocation{}, fmt.Errorf("invalid ip address %q", ip)
+	}
+
+	var record geoIPRecord
+	err := g.reader.Lookup(parsedIP, &record)
+	if err != nil {
+		return GeoLocation{}, err
+	}
+
+	location := GeoLocation{
+		Country:     strings.ToUpper(record.Country.ISOCode),
+		CountryName: record.Country.Names["en"],
+		City:        record.City.Names["en"],
+	}
+
+	if len(record.Subdivisions) > 0 {
+		location.Region = record.Subdivisions[0].Names["en"]
+	}
+
+	return location, nil
+}
+
+// Close releases the database.
+func (g *GeoIP) Close() error {
+	if g == nil {
+		return nil
+	}
+
+	return g.reader.Close()
+}
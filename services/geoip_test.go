@@ -0,0 +1,118 @@
+package services_test
+
+import (
+	"testing"
+
+	"github.com/Origho-precious/url-shortener/go/services"
+)
+
+// testdata/GeoIP2-City-Test.mmdb holds two networks: 81.2.69.0/24 in London,
+// England, United Kingdom, and 2001:218::/32 in Japan with a lower case
+// country code and no region or city.
+const testGeoIPPath = "testdata/GeoIP2-City-Test.mmdb"
+
+func TestGeoIPLookup(t *testing.T) {
+	geoIP, err := services.OpenGeoIP(testGeoIPPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { geoIP.Close() })
+
+	tests := []struct {
+		name   string
+		ip     string
+		want   services.GeoLocation
+		string string
+	}{
+		{
+			name: "known ipv4 address",
+			ip:   "81.2.69.142",
+			want: services.GeoLocation{
+				Country:     "GB",
+				CountryName: "United Kingdom",
+				Region:      "England",
+				City:        "London",
+			},
+			string: "London, England, United Kingdom",
+		},
+		{
+			name: "known ipv6 address",
+			ip:   "2001:218::1",
+			want: services.GeoLocation{
+				Country:     "JP",
+				CountryName: "Japan",
+			},
+			string: "Japan",
+		},
+		{name: "unknown public address", ip: "8.8.8.8"},
+		{name: "private address", ip: "10.0.0.1"},
+		{name: "loopback address", ip: "127.0.0.1"},
+		{name: "ipv6 loopback address", ip: "::1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			location, err := geoIP.Lookup(test.ip)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if location != test.want {
+				t.Errorf("Lookup(%q) = %+v, want %+v", test.ip, location, test.want)
+			}
+
+			if location.String() != test.string {
+				t.Errorf("String() = %q, want %q", location.String(), test.string)
+			}
+		})
+	}
+}
+
+func TestGeoIPLookupMalformedIP(t *testing.T) {
+	geoIP, err := services.OpenGeoIP(testGeoIPPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { geoIP.Close() })
+
+	for _, ip := range []string{"", "not an ip", "81.2.69", "256.1.1.1"} {
+		location, err := geoIP.Lookup(ip)
+		if err == nil {
+			t.Errorf("Lookup(%q) = %+v, want an error", ip, location)
+		}
+	}
+}
+
+func TestGeoIPWithoutDatabase(t *testing.T) {
+	t.Setenv("GIN_MODE", "test")
+	t.Setenv("GEOIP_DB_PATH", "")
+
+	geoIP, err := services.NewGeoIP()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if geoIP != nil {
+		t.Fatalf("NewGeoIP() = %v, want nil without GEOIP_DB_PATH", geoIP)
+	}
+
+	location, err := geoIP.Lookup("81.2.69.142")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if location != (services.GeoLocation{}) {
+		t.Errorf("Lookup on a nil GeoIP = %+v, want an empty location", location)
+	}
+
+	if err = geoIP.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOpenGeoIPMissingFile(t *testing.T) {
+	_, err := services.OpenGeoIP("testdata/missing.mmdb")
+	if err == nil {
+		t.Fatal("OpenGeoIP of a missing file succeeded, want an error")
+	}
+}
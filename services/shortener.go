@@ -1,45 +1,142 @@
 package services
 
 import (
+	"crypto/rand"
 	"crypto/sha256"
-	"encoding/base64"
 	"fmt"
+	"math/big"
 	"strings"
 	"sync"
 	"time"
 )
 
+const base62Chars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+const (
+	HashSlugStrategy    = "hash"
+	CounterSlugStrategy = "counter"
+	RandomSlugStrategy  = "random"
+	HashidSlugStrategy  = "hashid"
+	WordsSlugStrategy   = "words"
+)
+
+// SlugStrategies lists every strategy accepted by NewSlugGenerator.
+var SlugStrategies = []string{
+	HashSlugStrategy,
+	CounterSlugStrategy,
+	RandomSlugStrategy,
+	HashidSlugStrategy,
+	WordsSlugStrategy,
+}
+
+const (
+	hashSlugLength          = 10
+	defaultRandomSlugLength = 8
+	defaultHashidMinLength  = 6
+	defaultSlugWordCount    = 3
+)
+
 var (
 	counter   uint64
 	counterMu sync.Mutex
 )
 
-func base64ToBase62(base64Encoded string) string {
-	base62Chars := "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	base64Chars := "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789+/"
+// SlugGenerator produces candidate slugs for a long url. Candidates are not
+// guaranteed to be unique, the caller retries when one is already taken.
+type SlugGenerator interface {
+	Generate(longURL string) (string, error)
+}
+
+// Sequence hands out increasing numbers that survive restarts, e.g. a
+// counter kept in the database.
+type Sequence interface {
+	Next() (uint64, error)
+}
+
+type SlugGeneratorConfig struct {
+	Strategy string
+	// Length is the length of random slugs and the minimum length of
+	// hashid slugs.
+	Length   int
+	Salt     string
+	Sequence Sequence
+}
 
-	var base62Encoded string
-	for _, char := range base64Encoded {
-		index := strings.IndexRune(base64Chars, char)
-		if index != -1 {
-			base62Encoded += string(base62Chars[index])
+func NewSlugGenerator(cfg SlugGeneratorConfig) (SlugGenerator, error) {
+	switch cfg.Strategy {
+	case "", HashSlugStrategy:
+		return HashSlugGenerator{}, nil
+	case CounterSlugStrategy:
+		return CounterSlugGenerator{Sequence: cfg.Sequence}, nil
+	case RandomSlugStrategy:
+		return RandomSlugGenerator{Length: cfg.Length}, nil
+	case HashidSlugStrategy:
+		return HashidSlugGenerator{
+			Sequence:  cfg.Sequence,
+			Salt:      cfg.Salt,
+			MinLength: cfg.Length,
+		}, nil
+	case WordsSlugStrategy:
+		return WordsSlugGenerator{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported slug strategy: %s", cfg.Strategy)
+	}
+}
+
+func IsValidSlugStrategy(strategy string) bool {
+	for _, s := range SlugStrategies {
+		if s == strategy {
+			return true
 		}
 	}
 
-	return base62Encoded[:10]
+	return false
+}
+
+// encodeBase62 writes n in base62 using alphabet, left-padded with the
+// alphabet's zero digit up to minLength.
+func encodeBase62(n *big.Int, alphabet string, minLength int) string {
+	base := big.NewInt(int64(len(alphabet)))
+	rem := new(big.Int)
+	n = new(big.Int).Set(n)
+
+	var digits []byte
+	for n.Sign() > 0 {
+		n.DivMod(n, base, rem)
+		digits = append(digits, alphabet[rem.Int64()])
+	}
+
+	for len(digits) < minLength {
+		digits = append(digits, alphabet[0])
+	}
+
+	for i, j := 0, len(digits)-1; i < j; i, j = i+1, j-1 {
+		digits[i], digits[j] = digits[j], digits[i]
+	}
+
+	return string(digits)
+}
+
+// HashSlugGenerator derives a slug from a SHA-256 of the url, the current
+// time and a process-wide counter.
+type HashSlugGenerator struct{}
+
+func (HashSlugGenerator) Generate(longURL string) (string, error) {
+	return GenerateShortURL(longURL), nil
 }
 
 func GenerateShortURL(longURL string) string {
-	urlStr :=  fmt.Sprintf(
+	urlStr := fmt.Sprintf(
 		"%s-%d-%d", longURL, time.Now().UnixNano(), getUniqueCounter(),
 	)
 	hash := sha256.Sum256([]byte(urlStr))
 
-	base64EncodedString := base64.URLEncoding.EncodeToString(hash[:])
+	// Reduce the whole digest modulo 62^10 so every base62 digit is
+	// equally likely.
+	space := new(big.Int).Exp(big.NewInt(62), big.NewInt(hashSlugLength), nil)
+	n := new(big.Int).Mod(new(big.Int).SetBytes(hash[:]), space)
 
-	shortURL := base64ToBase62(base64EncodedString)
-
-	return shortURL
+	return encodeBase62(n, base62Chars, hashSlugLength)
 }
 
 func getUniqueCounter() uint64 {
@@ -49,3 +146,118 @@ func getUniqueCounter() uint64 {
 	counter++
 	return counter
 }
+
+// CounterSlugGenerator encodes the next value of a sequence in base62, which
+// gives the shortest possible slugs.
+type CounterSlugGenerator struct {
+	Sequence Sequence
+}
+
+func (g CounterSlugGenerator) Generate(_ string) (string, error) {
+	n, err := g.Sequence.Next()
+	if err != nil {
+		return "", err
+	}
+
+	return encodeBase62(new(big.Int).SetUint64(n), base62Chars, 1), nil
+}
+
+// RandomSlugGenerator picks every character with crypto/rand.
+type RandomSlugGenerator struct {
+	Length int
+}
+
+func (g RandomSlugGenerator) Generate(_ string) (string, error) {
+	length := g.Length
+	if length <= 0 {
+		length = defaultRandomSlugLength
+	}
+
+	max := big.NewInt(int64(len(base62Chars)))
+
+	var b strings.Builder
+	for i := 0; i < length; i++ {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+
+		b.WriteByte(base62Chars[n.Int64()])
+	}
+
+	return b.String(), nil
+}
+
+// HashidSlugGenerator turns the next value of a sequence into a fixed-length
+// slug that does not reveal the sequence. The value is scrambled with a
+// bijection modulo 62^MinLength and written with an alphabet shuffled by
+// Salt, so distinct values always give distinct slugs.
+type HashidSlugGenerator struct {
+	Sequence  Sequence
+	Salt      string
+	MinLength int
+}
+
+// hashidMultiplier is coprime with 62, which makes multiplying by it a
+// permutation of the values of a given slug length.
+var hashidMultiplier = big.NewInt(2654435761)
+
+func (g HashidSlugGenerator) Generate(_ string) (string, error) {
+	n, err := g.Sequence.Next()
+	if err != nil {
+		return "", err
+	}
+
+	length := g.MinLength
+	if length <= 0 {
+		length = defaultHashidMinLength
+	}
+
+	id := new(big.Int).SetUint64(n)
+	space := new(big.Int).Exp(big.NewInt(62), big.NewInt(int64(length)), nil)
+	for id.Cmp(space) >= 0 {
+		length++
+		space.Mul(space, big.NewInt(62))
+	}
+
+	scrambled := new(big.Int).Mul(id, hashidMultiplier)
+	scrambled.Mod(scrambled, space)
+
+	return encodeBase62(scrambled, shuffleAlphabet(base62Chars, g.Salt), length), nil
+}
+
+// shuffleAlphabet deterministically reorders alphabet using salt.
+func shuffleAlphabet(alphabet string, salt string) string {
+	chars := []byte(alphabet)
+	if salt == "" {
+		return alphabet
+	}
+
+	seed := sha256.Sum256([]byte(salt))
+	for i := len(chars) - 1; i > 0; i-- {
+		j := int(seed[i%len(seed)]) % (i + 1)
+		chars[i], chars[j] = chars[j], chars[i]
+	}
+
+	return string(chars)
+}
+
+// WordsSlugGenerator joins random words from a fixed list, e.g.
+// "brave-otter-lamp", for slugs that are easy to read out loud.
+type WordsSlugGenerator struct{}
+
+func (WordsSlugGenerator) Generate(_ string) (string, error) {
+	max := big.NewInt(int64(len(slugWords)))
+
+	words := make([]string, defaultSlugWordCount)
+	for i := range words {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+
+		words[i] = slugWords[n.Int64()]
+	}
+
+	return strings.Join(words, "-"), nil
+}
@@ -0,0 +1,38 @@
+package services
+
+// slugWords is the word list used by WordsSlugGenerator. It holds 256 short,
+// common words so three of them give roughly 16.7 million combinations.
+var slugWords = []string{
+	"able", "acid", "aged", "also", "area", "army", "away", "baby",
+	"back", "ball", "band", "bank", "base", "bath", "bear", "beat",
+	"bell", "belt", "best", "bird", "blow", "blue", "boat", "body",
+	"bone", "book", "boot", "born", "boss", "both", "bowl", "bulk",
+	"burn", "bush", "busy", "cake", "calm", "camp", "card", "care",
+	"cart", "case", "cash", "cast", "cell", "chat", "chip", "city",
+	"clay", "club", "coal", "coat", "code", "cold", "cook", "cool",
+	"cope", "copy", "core", "corn", "cost", "crew", "crop", "dark",
+	"data", "date", "dawn", "deal", "dear", "deck", "deep", "deer",
+	"desk", "dial", "diet", "disk", "dock", "door", "dose", "down",
+	"draw", "drop", "drum", "duck", "dust", "duty", "each", "earl",
+	"earn", "ease", "east", "easy", "edge", "else", "even", "ever",
+	"face", "fact", "fair", "farm", "fast", "feed", "feel", "fern",
+	"file", "film", "find", "fine", "fire", "firm", "fish", "five",
+	"flag", "flat", "flow", "foam", "folk", "food", "foot", "fork",
+	"form", "fort", "four", "free", "frog", "fuel", "full", "fund",
+	"gain", "game", "gate", "gear", "gift", "girl", "give", "glad",
+	"glow", "goal", "gold", "golf", "good", "gray", "grid", "grow",
+	"gulf", "hair", "half", "hall", "hand", "harp", "hawk", "head",
+	"heat", "helm", "herb", "hero", "high", "hill", "hint", "hold",
+	"home", "hook", "hope", "horn", "host", "hour", "huge", "idea",
+	"inch", "iron", "item", "jade", "jazz", "join", "joke", "jump",
+	"jury", "keen", "keep", "kind", "king", "kite", "knot", "lake",
+	"lamp", "land", "lane", "last", "lava", "lawn", "lead", "leaf",
+	"lean", "left", "lens", "lily", "lime", "line", "link", "lion",
+	"list", "live", "load", "loan", "lock", "loft", "long", "loop",
+	"lord", "luck", "lung", "mail", "main", "make", "malt", "many",
+	"mark", "mask", "meal", "meet", "mild", "mile", "milk", "mill",
+	"mind", "mint", "mist", "mode", "mood", "moon", "moss", "most",
+	"move", "much", "nail", "name", "navy", "near", "neat", "neck",
+	"nest", "news", "next", "nice", "nine", "node", "noon", "nose",
+	"note", "oak", "oath", "open", "oval", "oven", "pace", "pack",
+}
@@ -0,0 +1,16 @@
+// Package templates holds the HTML pages served to visitors of short urls.
+package templates
+
+import (
+	"embed"
+	"html/template"
+)
+
+//go:embed *.html
+var files embed.FS
+
+// Load parses every page, each available under its file name, e.g.
+// "password.html".
+func Load() (*template.Template, error) {
+	return template.ParseFS(files, "*.html")
+}
@@ -0,0 +1,240 @@
+// Package useragent turns User-Agent headers into the browser, operating
+// system and device recorded on visits.
+package useragent
+
+import (
+	"strings"
+
+	"github.com/Origho-precious/url-shortener/go/utils"
+)
+
+// Device classes reported in UserAgent.Device.
+const (
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceDesktop = "desktop"
+	DeviceBot     = "bot"
+)
+
+// Operating systems reported in UserAgent.OS.
+const (
+	OSiOS          = "iOS"
+	OSAndroid      = "Android"
+	OSWindows      = "Windows"
+	OSWindowsPhone = "Windows Phone"
+	OSMacOS        = "macOS"
+	OSChromeOS     = "ChromeOS"
+	OSLinux        = "Linux"
+)
+
+// UserAgent is what Parse could tell from a User-Agent header. Fields it
+// could not work out are left empty, apart from Browser which is "Other".
+type UserAgent struct {
+	Browser        string
+	BrowserVersion string
+	OS             string
+	OSVersion      string
+	// Device is one of the Device classes.
+	Device string
+	// Vendor is the maker of the device, e.g. "Apple" or "Samsung".
+	Vendor string
+}
+
+// browserTokens are checked in order, so browsers built on Chromium, Gecko or
+// WebKit are listed before the engine's own browser, whose token they also
+// carry.
+var browserTokens = []struct {
+	name  string
+	token string
+}{
+	{"Edge", "Edg/"},
+	{"Edge", "EdgA/"},
+	{"Edge", "EdgiOS/"},
+	{"Edge", "Edge/"},
+	{"Opera", "OPR/"},
+	{"Opera", "OPT/"},
+	{"Opera", "OPiOS/"},
+	{"Opera", "Opera/"},
+	{"Samsung Internet", "SamsungBrowser/"},
+	{"Yandex", "YaBrowser/"},
+	{"Vivaldi", "Vivaldi/"},
+	{"UC Browser", "UCBrowser/"},
+	{"Facebook", "FBAV/"},
+	{"Instagram", "Instagram "},
+	{"DuckDuckGo", "DuckDuckGo/"},
+	{"Firefox", "FxiOS/"},
+	{"Firefox", "Firefox/"},
+	{"Chrome", "CriOS/"},
+	{"Chromium", "Chromium/"},
+	{"Chrome", "Chrome/"},
+	{"Internet Explorer", "MSIE "},
+}
+
+// vendorTokens are checked in order against the user agent.
+var vendorTokens = []struct {
+	name  string
+	token string
+}{
+	{"Samsung", "SAMSUNG"},
+	{"Samsung", "SM-"},
+	{"Samsung", "GT-"},
+	{"Google", "Pixel"},
+	{"Google", "Nexus"},
+	{"Huawei", "HUAWEI"},
+	{"Huawei", "Huawei"},
+	{"Xiaomi", "Xiaomi"},
+	{"Xiaomi", "Redmi"},
+	{"Xiaomi", "POCO"},
+	{"Xiaomi", "; Mi "},
+	{"OnePlus", "OnePlus"},
+	{"OnePlus", "ONEPLUS"},
+	{"Oppo", "OPPO"},
+	{"Oppo", "CPH"},
+	{"Realme", "RMX"},
+	{"Vivo", "vivo"},
+	{"Motorola", "moto"},
+	{"Motorola", "Motorola"},
+	{"LG", "LG-"},
+	{"LG", "LM-"},
+	{"Sony", "Xperia"},
+	{"Nokia", "Nokia"},
+	{"Amazon", "Kindle"},
+	{"Amazon", "Silk/"},
+	{"Amazon", "KFTT"},
+	{"Tecno", "TECNO"},
+	{"Infinix", "Infinix"},
+	{"Itel", "itel"},
+}
+
+var windowsVersions = map[string]string{
+	"10.0": "10",
+	"6.3":  "8.1",
+	"6.2":  "8",
+	"6.1":  "7",
+	"6.0":  "Vista",
+	"5.2":  "XP",
+	"5.1":  "XP",
+}
+
+// Parse reads userAgent. It never fails, unknown user agents come back as a
+// desktop "Other" browser.
+func Parse(userAgent string) UserAgent {
+	ua := UserAgent{Browser: "Other"}
+
+	parseBrowser(userAgent, &ua)
+	parseOS(userAgent, &ua)
+	parseDevice(userAgent, &ua)
+	parseVendor(userAgent, &ua)
+
+	return ua
+}
+
+func parseBrowser(userAgent string, ua *UserAgent) {
+	for _, browser := range browserTokens {
+		if version, ok := versionAfter(userAgent, browser.token); ok {
+			ua.Browser = browser.name
+			ua.BrowserVersion = version
+			return
+		}
+	}
+
+	// Internet Explorer 11 dropped the MSIE token.
+	if strings.Contains(userAgent, "Trident/") {
+		ua.Browser = "Internet Explorer"
+		ua.BrowserVersion, _ = versionAfter(userAgent, "rv:")
+		return
+	}
+
+	// Safari only reports its version in the Version token, and in-app
+	// browsers on iOS leave both out.
+	if strings.Contains(userAgent, "Safari/") ||
+		(strings.Contains(userAgent, "AppleWebKit/") &&
+			strings.Contains(userAgent, "Mobile/")) {
+		ua.Browser = "Safari"
+		ua.BrowserVersion, _ = versionAfter(userAgent, "Version/")
+	}
+}
+
+func parseOS(userAgent string, ua *UserAgent) {
+	switch {
+	case strings.Contains(userAgent, "Windows Phone"):
+		ua.OS = OSWindowsPhone
+		ua.OSVersion, _ = versionAfter(userAgent, "Windows Phone ")
+	case strings.Contains(userAgent, "iPhone") ||
+		strings.Contains(userAgent, "iPad") ||
+		strings.Contains(userAgent, "iPod"):
+		ua.OS = OSiOS
+		version, ok := versionAfter(userAgent, "iPhone OS ")
+		if !ok {
+			version, _ = versionAfter(userAgent, "CPU OS ")
+		}
+		ua.OSVersion = strings.ReplaceAll(version, "_", ".")
+	case strings.Contains(userAgent, "Android"):
+		ua.OS = OSAndroid
+		ua.OSVersion, _ = versionAfter(userAgent, "Android ")
+	case strings.Contains(userAgent, "Windows"):
+		ua.OS = OSWindows
+		version, _ := versionAfter(userAgent, "Windows NT ")
+		ua.OSVersion = windowsVersions[version]
+	case strings.Contains(userAgent, "CrOS"):
+		ua.OS = OSChromeOS
+	case strings.Contains(userAgent, "Macintosh") ||
+		strings.Contains(userAgent, "Mac OS X"):
+		ua.OS = OSMacOS
+		version, _ := versionAfter(userAgent, "Mac OS X ")
+		ua.OSVersion = strings.ReplaceAll(version, "_", ".")
+	case strings.Contains(userAgent, "Linux") ||
+		strings.Contains(userAgent, "X11"):
+		ua.OS = OSLinux
+	}
+}
+
+func parseDevice(userAgent string, ua *UserAgent) {
+	lower := strings.ToLower(userAgent)
+
+	switch {
+	case utils.IsBot(userAgent):
+		ua.Device = DeviceBot
+	case strings.Contains(lower, "ipad") || strings.Contains(lower, "tablet") ||
+		strings.Contains(lower, "kindle") || strings.Contains(lower, "silk/") ||
+		(ua.OS == OSAndroid && !strings.Contains(lower, "mobile")):
+		ua.Device = DeviceTablet
+	case strings.Contains(lower, "mobi") || strings.Contains(lower, "iphone") ||
+		strings.Contains(lower, "ipod") || ua.OS == OSAndroid ||
+		ua.OS == OSWindowsPhone:
+		ua.Device = DeviceMobile
+	default:
+		ua.Device = DeviceDesktop
+	}
+}
+
+func parseVendor(userAgent string, ua *UserAgent) {
+	if ua.OS == OSiOS || ua.OS == OSMacOS {
+		ua.Vendor = "Apple"
+		return
+	}
+
+	for _, vendor := range vendorTokens {
+		if strings.Contains(userAgent, vendor.token) {
+			ua.Vendor = vendor.name
+			return
+		}
+	}
+}
+
+// versionAfter returns the version following token in userAgent, up to the
+// next space, semicolon or parenthesis.
+func versionAfter(userAgent string, token string) (string, bool) {
+	i := strings.Index(userAgent, token)
+	if i < 0 {
+		return "", false
+	}
+
+	rest := userAgent[i+len(token):]
+	end := strings.IndexAny(rest, " ;)(")
+	if end >= 0 {
+		rest = rest[:end]
+	}
+
+	return rest, true
+}
@@ -0,0 +1,312 @@
+package useragent_test
+
+import (
+	"testing"
+
+	"github.com/Origho-precious/url-shortener/go/useragent"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name      string
+		userAgent string
+		want      useragent.UserAgent
+	}{
+		{
+			name:      "Chrome on Windows",
+			userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			want: useragent.UserAgent{
+				Browser: "Chrome", BrowserVersion: "120.0.0.0",
+				OS: useragent.OSWindows, OSVersion: "10",
+				Device: useragent.DeviceDesktop,
+			},
+		},
+		{
+			name:      "Edge on Windows",
+			userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.2210.91",
+			want: useragent.UserAgent{
+				Browser: "Edge", BrowserVersion: "120.0.2210.91",
+				OS: useragent.OSWindows, OSVersion: "10",
+				Device: useragent.DeviceDesktop,
+			},
+		},
+		{
+			name:      "Opera on Windows",
+			userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 OPR/106.0.0.0",
+			want: useragent.UserAgent{
+				Browser: "Opera", BrowserVersion: "106.0.0.0",
+				OS: useragent.OSWindows, OSVersion: "10",
+				Device: useragent.DeviceDesktop,
+			},
+		},
+		{
+			name:      "Internet Explorer 11 on Windows 7",
+			userAgent: "Mozilla/5.0 (Windows NT 6.1; WOW64; Trident/7.0; rv:11.0) like Gecko",
+			want: useragent.UserAgent{
+				Browser: "Internet Explorer", BrowserVersion: "11.0",
+				OS: useragent.OSWindows, OSVersion: "7",
+				Device: useragent.DeviceDesktop,
+			},
+		},
+		{
+			name:      "Firefox on macOS",
+			userAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 10.15; rv:121.0) Gecko/20100101 Firefox/121.0",
+			want: useragent.UserAgent{
+				Browser: "Firefox", BrowserVersion: "121.0",
+				OS: useragent.OSMacOS, OSVersion: "10.15",
+				Device: useragent.DeviceDesktop, Vendor: "Apple",
+			},
+		},
+		{
+			name:      "Safari on macOS",
+			userAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Safari/605.1.15",
+			want: useragent.UserAgent{
+				Browser: "Safari", BrowserVersion: "17.2",
+				OS: useragent.OSMacOS, OSVersion: "10.15.7",
+				Device: useragent.DeviceDesktop, Vendor: "Apple",
+			},
+		},
+		{
+			name:      "Firefox on Linux",
+			userAgent: "Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0",
+			want: useragent.UserAgent{
+				Browser: "Firefox", BrowserVersion: "121.0",
+				OS: useragent.OSLinux, Device: useragent.DeviceDesktop,
+			},
+		},
+		{
+			name:      "Chrome on a Chromebook",
+			userAgent: "Mozilla/5.0 (X11; CrOS x86_64 14541.0.0) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			want: useragent.UserAgent{
+				Browser: "Chrome", BrowserVersion: "120.0.0.0",
+				OS: useragent.OSChromeOS, Device: useragent.DeviceDesktop,
+			},
+		},
+		{
+			name:      "Safari on an iPhone",
+			userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_2_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Mobile/15E148 Safari/604.1",
+			want: useragent.UserAgent{
+				Browser: "Safari", BrowserVersion: "17.2",
+				OS: useragent.OSiOS, OSVersion: "17.2.1",
+				Device: useragent.DeviceMobile, Vendor: "Apple",
+			},
+		},
+		{
+			name:      "Chrome on an iPad",
+			userAgent: "Mozilla/5.0 (iPad; CPU OS 17_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/120.0.6099.119 Mobile/15E148 Safari/604.1",
+			want: useragent.UserAgent{
+				Browser: "Chrome", BrowserVersion: "120.0.6099.119",
+				OS: useragent.OSiOS, OSVersion: "17.2",
+				Device: useragent.DeviceTablet, Vendor: "Apple",
+			},
+		},
+		{
+			name:      "Chrome on a Pixel",
+			userAgent: "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.6099.144 Mobile Safari/537.36",
+			want: useragent.UserAgent{
+				Browser: "Chrome", BrowserVersion: "120.0.6099.144",
+				OS: useragent.OSAndroid, OSVersion: "14",
+				Device: useragent.DeviceMobile, Vendor: "Google",
+			},
+		},
+		{
+			name:      "Samsung Internet on a Galaxy phone",
+			userAgent: "Mozilla/5.0 (Linux; Android 13; SM-S918B) AppleWebKit/537.36 (KHTML, like Gecko) SamsungBrowser/23.0 Chrome/115.0.0.0 Mobile Safari/537.36",
+			want: useragent.UserAgent{
+				Browser: "Samsung Internet", BrowserVersion: "23.0",
+				OS: useragent.OSAndroid, OSVersion: "13",
+				Device: useragent.DeviceMobile, Vendor: "Samsung",
+			},
+		},
+		{
+			name:      "Chrome on a Galaxy tablet",
+			userAgent: "Mozilla/5.0 (Linux; Android 13; SM-X700) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			want: useragent.UserAgent{
+				Browser: "Chrome", BrowserVersion: "120.0.0.0",
+				OS: useragent.OSAndroid, OSVersion: "13",
+				Device: useragent.DeviceTablet, Vendor: "Samsung",
+			},
+		},
+		{
+			name:      "Silk on a Kindle Fire",
+			userAgent: "Mozilla/5.0 (Linux; Android 9; KFTRWI) AppleWebKit/537.36 (KHTML, like Gecko) Silk/120.3.1 like Chrome/120.0.6099.217 Safari/537.36",
+			want: useragent.UserAgent{
+				Browser: "Chrome", BrowserVersion: "120.0.6099.217",
+				OS: useragent.OSAndroid, OSVersion: "9",
+				Device: useragent.DeviceTablet, Vendor: "Amazon",
+			},
+		},
+		{
+			name:      "Chrome on a Cubot phone",
+			userAgent: "Mozilla/5.0 (Linux; Android 11; CUBOT KINGKONG 7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.6099.144 Mobile Safari/537.36",
+			want: useragent.UserAgent{
+				Browser: "Chrome", BrowserVersion: "120.0.6099.144",
+				OS: useragent.OSAndroid, OSVersion: "11",
+				Device: useragent.DeviceMobile,
+			},
+		},
+		{
+			name:      "DuckDuckGo on an iPhone",
+			userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Mobile/15E148 DuckDuckGo/7 Safari/605.1.15",
+			want: useragent.UserAgent{
+				Browser: "DuckDuckGo", BrowserVersion: "7",
+				OS: useragent.OSiOS, OSVersion: "17.2",
+				Device: useragent.DeviceMobile, Vendor: "Apple",
+			},
+		},
+		{
+			name:      "DuckDuckGo on Android",
+			userAgent: "Mozilla/5.0 (Linux; Android 14; Pixel 7) AppleWebKit/537.36 (KHTML, like Gecko) Version/4.0 Chrome/120.0.0.0 Mobile DuckDuckGo/5 Safari/537.36",
+			want: useragent.UserAgent{
+				Browser: "DuckDuckGo", BrowserVersion: "5",
+				OS: useragent.OSAndroid, OSVersion: "14",
+				Device: useragent.DeviceMobile, Vendor: "Google",
+			},
+		},
+		{
+			name:      "Yandex search app on Android",
+			userAgent: "Mozilla/5.0 (Linux; Android 12; M2101K6G) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/110.0.5481.208 YaApp_Android/23.22.1 YaSearchBrowser/23.22.1 BroPP/1.0 SA/3 Mobile Safari/537.36",
+			want: useragent.UserAgent{
+				Browser: "Chrome", BrowserVersion: "110.0.5481.208",
+				OS: useragent.OSAndroid, OSVersion: "12",
+				Device: useragent.DeviceMobile,
+			},
+		},
+		{
+			name:      "Sogou browser on Android",
+			userAgent: "Mozilla/5.0 (Linux; Android 10; V2020A Build/QP1A.190711.020; wv) AppleWebKit/537.36 (KHTML, like Gecko) Version/4.0 Chrome/86.0.4240.99 Mobile Safari/537.36 SogouMobileBrowser/5.30.17",
+			want: useragent.UserAgent{
+				Browser: "Chrome", BrowserVersion: "86.0.4240.99",
+				OS: useragent.OSAndroid, OSVersion: "10",
+				Device: useragent.DeviceMobile,
+			},
+		},
+		{
+			name:      "Facebook in-app browser on an iPhone",
+			userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_1_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148 [FBAN/FBIOS;FBAV/442.0.0.36.118;FBBV/551017424;FBDV/iPhone15,2;FBMD/iPhone;FBSN/iOS;FBSV/17.1.2;FBSS/3;FBID/phone;FBLC/en_US;FBOP/5]",
+			want: useragent.UserAgent{
+				Browser: "Facebook", BrowserVersion: "442.0.0.36.118",
+				OS: useragent.OSiOS, OSVersion: "17.1.2",
+				Device: useragent.DeviceMobile, Vendor: "Apple",
+			},
+		},
+		{
+			name:      "Pinterest in-app browser on an iPhone",
+			userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148 [Pinterest/iOS]",
+			want: useragent.UserAgent{
+				Browser: "Safari", OS: useragent.OSiOS, OSVersion: "17.1",
+				Device: useragent.DeviceMobile, Vendor: "Apple",
+			},
+		},
+		{
+			name:      "Googlebot",
+			userAgent: "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			want: useragent.UserAgent{
+				Browser: "Other", Device: useragent.DeviceBot,
+			},
+		},
+		{
+			name:      "Googlebot smartphone",
+			userAgent: "Mozilla/5.0 (Linux; Android 6.0.1; Nexus 5X Build/MMB29P) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.6099.71 Mobile Safari/537.36 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			want: useragent.UserAgent{
+				Browser: "Chrome", BrowserVersion: "120.0.6099.71",
+				OS: useragent.OSAndroid, OSVersion: "6.0.1",
+				Device: useragent.DeviceBot, Vendor: "Google",
+			},
+		},
+		{
+			name:      "DuckDuckBot",
+			userAgent: "DuckDuckBot/1.1; (+http://duckduckgo.com/duckduckbot.html)",
+			want: useragent.UserAgent{
+				Browser: "Other", Device: useragent.DeviceBot,
+			},
+		},
+		{
+			name:      "DuckAssistBot",
+			userAgent: "DuckAssistBot/1.0; (+http://duckduckgo.com/duckassistbot.html)",
+			want: useragent.UserAgent{
+				Browser: "Other", Device: useragent.DeviceBot,
+			},
+		},
+		{
+			name:      "YandexImages",
+			userAgent: "Mozilla/5.0 (compatible; YandexImages/3.0; +http://yandex.com/bots)",
+			want: useragent.UserAgent{
+				Browser: "Other", Device: useragent.DeviceBot,
+			},
+		},
+		{
+			name:      "Sogou web spider",
+			userAgent: "Sogou web spider/4.0(+http://www.sogou.com/docs/help/webmasters.htm#07)",
+			want: useragent.UserAgent{
+				Browser: "Other", Device: useragent.DeviceBot,
+			},
+		},
+		{
+			name:      "Slack link unfurler",
+			userAgent: "Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)",
+			want: useragent.UserAgent{
+				Browser: "Other", Device: useragent.DeviceBot,
+			},
+		},
+		{
+			name:      "WhatsApp link preview",
+			userAgent: "WhatsApp/2.23.20.0 A",
+			want: useragent.UserAgent{
+				Browser: "Other", Device: useragent.DeviceBot,
+			},
+		},
+		{
+			name:      "UptimeRobot",
+			userAgent: "Mozilla/5.0+(compatible; UptimeRobot/2.0; http://www.uptimerobot.com/)",
+			want: useragent.UserAgent{
+				Browser: "Other", Device: useragent.DeviceBot,
+			},
+		},
+		{
+			name:      "Uptime Kuma",
+			userAgent: "Uptime-Kuma/1.23.11",
+			want: useragent.UserAgent{
+				Browser: "Other", Device: useragent.DeviceBot,
+			},
+		},
+		{
+			name:      "Headless Chrome",
+			userAgent: "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) HeadlessChrome/120.0.6099.28 Safari/537.36",
+			want: useragent.UserAgent{
+				Browser: "Chrome", BrowserVersion: "120.0.6099.28",
+				OS: useragent.OSLinux, Device: useragent.DeviceBot,
+			},
+		},
+		{
+			name:      "curl",
+			userAgent: "curl/8.4.0",
+			want: useragent.UserAgent{
+				Browser: "Other", Device: useragent.DeviceBot,
+			},
+		},
+		{
+			name:      "python requests",
+			userAgent: "python-requests/2.31.0",
+			want: useragent.UserAgent{
+				Browser: "Other", Device: useragent.DeviceBot,
+			},
+		},
+		{
+			name:      "no user agent",
+			userAgent: "",
+			want: useragent.UserAgent{
+				Browser: "Other", Device: useragent.DeviceBot,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := useragent.Parse(test.userAgent)
+			if got != test.want {
+				t.Errorf("Parse(%q)\ngot  %+v\nwant %+v", test.userAgent, got, test.want)
+			}
+		})
+	}
+}
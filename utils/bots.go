@@ -0,0 +1,118 @@
+package utils
+
+import "strings"
+
+// botPatterns are lower case user agent fragments of crawlers, uptime
+// monitors, link checkers and http libraries. Keep the list sorted by kind
+// and add to it as new bots show up in visit logs. Fragments must not match
+// real browsers, e.g. "duckduckgo" would match the DuckDuckGo browser where
+// "duckduckbot" only matches its crawler.
+var botPatterns = []string{
+	// Generic markers most crawlers put in their user agent.
+	"bot",
+	"crawl",
+	"spider",
+	"slurp",
+	"scrape",
+	"fetcher",
+	"archiver",
+	"headlesschrome",
+
+	// Search engines and SEO tools not matched above.
+	"mediapartners-google",
+	"adsbot-google",
+	"bingpreview",
+	"google web preview",
+	"compatible; yandex",
+	"baiduspider",
+	"duckduckbot",
+	"duckassistbot",
+	"sogou web spider",
+	"exabot",
+	"semrush",
+	"ahrefs",
+	"mj12bot",
+	"dotbot",
+	"petalbot",
+	"bytespider",
+	"gptbot",
+	"ccbot",
+
+	// Uptime monitors and link checkers.
+	"pingdom",
+	"uptimerobot",
+	"statuscake",
+	"site24x7",
+	"freshping",
+	"betteruptime",
+	"newrelicpinger",
+	"datadog",
+	"uptime-kuma",
+	"updown.io",
+	"hetrixtools",
+	"zabbix",
+	"checkly",
+	"check_http",
+	"linkchecker",
+	"lighthouse",
+	"pagespeed",
+	"gtmetrix",
+
+	// Http libraries and command line tools.
+	"curl/",
+	"wget/",
+	"httpie/",
+	"python-requests",
+	"python-urllib",
+	"aiohttp",
+	"go-http-client",
+	"okhttp",
+	"java/",
+	"apache-httpclient",
+	"libwww-perl",
+	"node-fetch",
+	"axios/",
+	"got (",
+	"undici",
+	"ruby",
+	"php/",
+	"guzzlehttp",
+	"postmanruntime",
+	"insomnia",
+	"phantomjs",
+	"puppeteer",
+	"playwright",
+	"selenium",
+}
+
+// humanPatterns are lower case user agent fragments of real browsers and
+// devices that botPatterns would match, e.g. Cubot phones.
+var humanPatterns = []string{
+	"cubot",
+}
+
+// IsBot reports whether userAgent belongs to a crawler, monitor, link
+// unfurler or script rather than a person. Requests without a user agent are
+// treated as bots, browsers always send one.
+func IsBot(userAgent string) bool {
+	ua := strings.ToLower(strings.TrimSpace(userAgent))
+	if ua == "" {
+		return true
+	}
+
+	for _, pattern := range humanPatterns {
+		ua = strings.ReplaceAll(ua, pattern, "")
+	}
+
+	if IsLinkUnfurler(ua) {
+		return true
+	}
+
+	for _, pattern := range botPatterns {
+		if strings.Contains(ua, pattern) {
+			return true
+		}
+	}
+
+	return false
+}
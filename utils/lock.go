@@ -0,0 +1,46 @@
+package utils
+
+import "sync"
+
+// KeyedMutex hands out a mutex per key, created on demand and dropped once
+// nobody holds or waits for it. It is kept in memory, so it only serialises
+// work within one instance of the server.
+type KeyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*keyedLock
+}
+
+type keyedLock struct {
+	sync.Mutex
+	users int
+}
+
+func NewKeyedMutex() *KeyedMutex {
+	return &KeyedMutex{locks: map[string]*keyedLock{}}
+}
+
+// Lock locks key and returns the function unlocking it.
+func (k *KeyedMutex) Lock(key string) func() {
+	k.mu.Lock()
+	lock, ok := k.locks[key]
+	if !ok {
+		lock = &keyedLock{}
+		k.locks[key] = lock
+	}
+	lock.users++
+	k.mu.Unlock()
+
+	lock.Lock()
+
+	return func() {
+		lock.Unlock()
+
+		k.mu.Lock()
+		defer k.mu.Unlock()
+
+		lock.users--
+		if lock.users == 0 {
+			delete(k.locks, key)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// FailureLimiter blocks a key once it has failed too often within a window,
+// e.g. to slow down password guessing. It is kept in memory, so every
+// instance of the server counts failures on its own.
+//
+// Attempts are counted before they are checked, so concurrent attempts
+// cannot get past the limit, and the ones that succeed are handed back.
+type FailureLimiter struct {
+	mu          sync.Mutex
+	maxFailures int
+	window      time.Duration
+	failures    map[string][]time.Time
+}
+
+func NewFailureLimiter(maxFailures int, window time.Duration) *FailureLimiter {
+	l := &FailureLimiter{
+		maxFailures: maxFailures,
+		window:      window,
+		failures:    map[string][]time.Time{},
+	}
+
+	go l.prune()
+
+	return l
+}
+
+// Attempt counts an attempt for key, unless key has used up its failures.
+// It then reports false and how long until key may try again.
+func (l *FailureLimiter) Attempt(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+
+	failures := l.recent(key, now)
+	if len(failures) >= l.maxFailures {
+		return false, failures[0].Add(l.window).Sub(now)
+	}
+
+	l.failures[key] = append(failures, now)
+
+	return true, 0
+}
+
+// Forgive hands back the latest attempt of key, e.g. when it succeeded or
+// was never made.
+func (l *FailureLimiter) Forgive(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	failures := l.recent(key, time.Now())
+	if len(failures) > 0 {
+		l.failures[key] = failures[:len(failures)-1]
+	}
+}
+
+// Reset forgets the failures of key, e.g. after a successful attempt.
+func (l *FailureLimiter) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.failures, key)
+}
+
+func (l *FailureLimiter) recent(key string, now time.Time) []time.Time {
+	failures := l.failures[key]
+
+	for len(failures) > 0 && now.Sub(failures[0]) >= l.window {
+		failures = failures[1:]
+	}
+
+	if len(failures) == 0 {
+		delete(l.failures, key)
+	} else {
+		l.failures[key] = failures
+	}
+
+	return failures
+}
+
+// prune drops keys that have gone quiet so the map does not grow forever.
+func (l *FailureLimiter) prune() {
+	ticker := time.NewTicker(l.window)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		l.mu.Lock()
+
+		now := time.Now()
+		for key := range l.failures {
+			l.recent(key, now)
+		}
+
+		l.mu.Unlock()
+	}
+}
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"fmt"
+	"time"
+	// Embedded so timezones resolve on hosts without a zoneinfo database.
+	_ "time/tzdata"
+)
+
+// localLinkTimeLayouts are the accepted layouts without a UTC offset. They
+// are read in the timezone passed to ParseLinkTime.
+var localLinkTimeLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	// The DD-MM-YYYY layout expiry dates used to be limited to.
+	"02-01-2006",
+}
+
+// LoadTimezone returns the location of an IANA timezone name such as
+// "Africa/Lagos", or UTC when timezone is empty.
+func LoadTimezone(timezone string) (*time.Location, error) {
+	if timezone == "" {
+		return time.UTC, nil
+	}
+
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone: %s", timezone)
+	}
+
+	return location, nil
+}
+
+// ParseLinkTime parses the activation or expiry time of a link. RFC 3339
+// values carry their own offset; other layouts are read in timezone, an
+// IANA name such as "Africa/Lagos" that defaults to UTC.
+func ParseLinkTime(value string, timezone string) (time.Time, error) {
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed, nil
+	}
+
+	location, err := LoadTimezone(timezone)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	for _, layout := range localLinkTimeLayouts {
+		if parsed, err := time.ParseInLocation(layout, value, location); err == nil {
+			return parsed, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf(
+		"%q is not an RFC 3339 time, a YYYY-MM-DDTHH:MM date-time or a DD-MM-YYYY date",
+		value,
+	)
+}
@@ -0,0 +1,36 @@
+package utils
+
+import "strings"
+
+// linkUnfurlers are user agent fragments of the crawlers chat apps and social
+// networks send to build link previews.
+var linkUnfurlers = []string{
+	"slackbot",
+	"twitterbot",
+	"facebookexternalhit",
+	"facebot",
+	"discordbot",
+	"whatsapp",
+	"telegrambot",
+	"linkedinbot",
+	"skypeuripreview",
+	"microsoftpreview",
+	"pinterestbot",
+	"redditbot",
+	"embedly",
+	"mastodon",
+	"iframely",
+}
+
+// IsLinkUnfurler reports whether userAgent belongs to a crawler building a
+// link preview.
+func IsLinkUnfurler(userAgent string) bool {
+	ua := strings.ToLower(userAgent)
+	for _, unfurler := range linkUnfurlers {
+		if strings.Contains(ua, unfurler) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+const defaultURLScheme = "https"
+
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// URLValidationError describes why a url was rejected.
+type URLValidationError struct {
+	Url    string
+	Reason string
+}
+
+func (e *URLValidationError) Error() string {
+	return fmt.Sprintf("url is invalid: %s (%s)", e.Url, e.Reason)
+}
+
+// NormalizeURL validates rawURL and returns it in canonical form. A missing
+// scheme defaults to https, the scheme and host are lowercased, IDN hosts are
+// converted to punycode and default ports are dropped. Path, query and
+// fragment are kept exactly as given since they are often case-sensitive.
+func NormalizeURL(rawURL string) (string, error) {
+	invalid := func(reason string) error {
+		return &URLValidationError{Url: rawURL, Reason: reason}
+	}
+
+	trimmed := strings.TrimSpace(rawURL)
+	if trimmed == "" {
+		return "", invalid("url is empty")
+	}
+
+	if strings.HasPrefix(trimmed, "//") {
+		trimmed = defaultURLScheme + ":" + trimmed
+	} else if !strings.Contains(trimmed, "://") {
+		trimmed = defaultURLScheme + "://" + trimmed
+	}
+
+	u, err := url.Parse(trimmed)
+	if err != nil {
+		return "", invalid("url could not be parsed")
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	if _, ok := defaultPorts[u.Scheme]; !ok {
+		return "", invalid("scheme must be http or https")
+	}
+
+	// "https://trusted.com@evil.com" style urls are a common phishing trick.
+	if u.User != nil {
+		return "", invalid("credentials are not allowed in urls")
+	}
+
+	hostname := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if hostname == "" {
+		return "", invalid("host is missing")
+	}
+
+	if ip := net.ParseIP(hostname); ip != nil {
+		if ip.To4() == nil {
+			hostname = "[" + hostname + "]"
+		}
+	} else {
+		hostname, err = idna.Lookup.ToASCII(hostname)
+		if err != nil {
+			return "", invalid("host is not a valid domain name")
+		}
+
+		if !strings.Contains(hostname, ".") {
+			return "", invalid("host must be a fully qualified domain name")
+		}
+	}
+
+	port := u.Port()
+	if port != "" {
+		n, err := strconv.Atoi(port)
+		if err != nil || n < 1 || n > 65535 {
+			return "", invalid("port must be between 1 and 65535")
+		}
+	}
+
+	if port == "" || port == defaultPorts[u.Scheme] {
+		u.Host = hostname
+	} else {
+		u.Host = hostname + ":" + port
+	}
+
+	return u.String(), nil
+}